@@ -3,8 +3,8 @@ package appcenter
 import (
 	"fmt"
 
-	"github.com/bitrise-io/appcenter/client"
-	"github.com/bitrise-io/appcenter/model"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/client"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/model"
 )
 
 // AppAPI ...
@@ -26,7 +26,7 @@ func (a AppAPI) NewRelease() (model.Release, error) {
 	releaseID, err := a.API.CreateRelease(a.ReleaseOptions)
 	if err != nil {
 		return model.Release{},
-			fmt.Errorf("failed to create new release on app: %s, owner: %s, %v",
+			fmt.Errorf("failed to create new release on app: %s, owner: %s, %w",
 				a.ReleaseOptions.App.AppName,
 				a.ReleaseOptions.App.Owner,
 				err)
@@ -35,6 +35,36 @@ func (a AppAPI) NewRelease() (model.Release, error) {
 	return a.API.GetAppReleaseDetails(a.ReleaseOptions.App, releaseID)
 }
 
+// Details ...
+func (a AppAPI) Details() (model.AppDetails, error) {
+	return a.API.GetAppDetails(a.ReleaseOptions.App)
+}
+
+// Releases ...
+func (a AppAPI) Releases() ([]model.Release, error) {
+	return a.API.GetReleases(a.ReleaseOptions.App)
+}
+
+// ReleaseDetails ...
+func (a AppAPI) ReleaseDetails(releaseID int) (model.Release, error) {
+	return a.API.GetAppReleaseDetails(a.ReleaseOptions.App, releaseID)
+}
+
+// LatestRelease ...
+func (a AppAPI) LatestRelease() (model.Release, error) {
+	return a.API.GetLatestRelease(a.ReleaseOptions.App)
+}
+
+// LatestReleaseInGroup ...
+func (a AppAPI) LatestReleaseInGroup(groupName string) (model.Release, error) {
+	return a.API.GetLatestReleaseInGroup(groupName, a.ReleaseOptions.App)
+}
+
+// ReleasesInGroup ...
+func (a AppAPI) ReleasesInGroup(groupName string) ([]model.Release, error) {
+	return a.API.GetReleasesInGroup(groupName, a.ReleaseOptions.App)
+}
+
 // Groups ...
 func (a AppAPI) Groups(name string) (model.Group, error) {
 	return a.API.GetGroupByName(name, a.ReleaseOptions.App)
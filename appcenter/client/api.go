@@ -12,14 +12,15 @@ import (
 
 	"golang.org/x/sync/semaphore"
 
-	"github.com/bitrise-io/appcenter/model"
-	"github.com/bitrise-io/appcenter/util"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/model"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/util"
 )
 
 const (
-	maxAttempts               = 100
-	maxConcurrentChunkUploads = 10
-	releaseFailedID           = -1
+	// defaultUploadStatusMaxAttempts is how many times the upload status is checked until the release is ready, by default.
+	defaultUploadStatusMaxAttempts = 100
+	maxConcurrentChunkUploads      = 10
+	releaseFailedID                = -1
 )
 
 type fileAssetResponse struct {
@@ -34,14 +35,82 @@ type fileAssetResponse struct {
 type API struct {
 	Client  Client
 	baseURL string
+	// uploadStatusMaxAttempts is how many times the upload status is checked until the release is ready.
+	uploadStatusMaxAttempts int
+}
+
+// Option ...
+type Option func(*API)
+
+// WithBaseURL sets the base URL of the App Center API, for example to route the requests through a gateway or to a mock server.
+func WithBaseURL(baseURL string) Option {
+	return func(api *API) {
+		api.baseURL = baseURL
+	}
+}
+
+// WithTransport sets the transport the requests are sent with, for example one using a proxy or custom TLS settings.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(api *API) {
+		api.Client.setTransport(transport)
+	}
+}
+
+// WithRetryPolicies sets the retry policy of the control plane JSON calls and of the data plane chunk and symbol uploads.
+func WithRetryPolicies(api, upload RetryPolicy) Option {
+	return func(a *API) {
+		a.Client.setRetryPolicies(api, upload)
+	}
+}
+
+// WithUploadStatusMaxAttempts sets how many times the upload status is checked until App Center finishes processing the release.
+func WithUploadStatusMaxAttempts(attempts int) Option {
+	return func(api *API) {
+		api.uploadStatusMaxAttempts = attempts
+	}
 }
 
 // CreateAPIWithClientParams ...
-func CreateAPIWithClientParams(token string) API {
-	return API{
-		Client:  NewClient(token),
-		baseURL: baseURL,
+func CreateAPIWithClientParams(token string, opts ...Option) (API, error) {
+	api := API{
+		Client:                  NewClient(token),
+		uploadStatusMaxAttempts: defaultUploadStatusMaxAttempts,
+	}
+
+	for _, opt := range opts {
+		opt(&api)
+	}
+
+	if api.uploadStatusMaxAttempts < 1 {
+		return API{}, fmt.Errorf("upload status max attempts has to be at least 1, got: %d", api.uploadStatusMaxAttempts)
+	}
+
+	baseURL, err := util.ParseBaseURL(api.baseURL)
+	if err != nil {
+		return API{}, fmt.Errorf("invalid API base URL: %s", err)
+	}
+	api.baseURL = baseURL
+
+	return api, nil
+}
+
+// GetAppDetails ...
+func (api API) GetAppDetails(app model.App) (model.AppDetails, error) {
+	var (
+		getURL      = fmt.Sprintf("%s/v0.1/apps/%s/%s", api.baseURL, app.Owner, app.AppName)
+		getResponse model.AppDetails
+	)
+
+	statusCode, err := api.Client.jsonRequest("get app details", http.MethodGet, getURL, nil, &getResponse)
+	if err != nil {
+		return model.AppDetails{}, err
 	}
+
+	if statusCode != http.StatusOK {
+		return model.AppDetails{}, fmt.Errorf("invalid status code: %d, url: %s, body: %v", statusCode, Redact(getURL), getResponse)
+	}
+
+	return getResponse, nil
 }
 
 // GetAppReleaseDetails ...
@@ -52,18 +121,89 @@ func (api API) GetAppReleaseDetails(app model.App, releaseID int) (model.Release
 		release        model.Release
 	)
 
-	statusCode, err := api.Client.jsonRequest(http.MethodGet, releaseShowURL, nil, &release)
+	statusCode, err := api.Client.jsonRequest("get release details", http.MethodGet, releaseShowURL, nil, &release)
 	if err != nil {
 		return model.Release{}, err
 	}
 
 	if statusCode != http.StatusOK {
-		return model.Release{}, fmt.Errorf("invalid status code: %d, url: %s, body: %v", statusCode, releaseShowURL, release)
+		return model.Release{}, fmt.Errorf("invalid status code: %d, url: %s, body: %v", statusCode, Redact(releaseShowURL), release)
 	}
 
 	return release, err
 }
 
+// GetReleases returns every release of the app
+func (api API) GetReleases(app model.App) ([]model.Release, error) {
+	var (
+		getURL      = fmt.Sprintf("%s/v0.1/apps/%s/%s/releases", api.baseURL, app.Owner, app.AppName)
+		getResponse []model.Release
+	)
+
+	statusCode, err := api.Client.jsonRequest("get releases", http.MethodGet, getURL, nil, &getResponse)
+	if err != nil {
+		return []model.Release{}, err
+	}
+
+	if statusCode != http.StatusOK {
+		return []model.Release{}, fmt.Errorf("invalid status code: %d, url: %s, body: %v", statusCode, Redact(getURL), getResponse)
+	}
+
+	return getResponse, nil
+}
+
+// GetLatestRelease returns the latest release of the app, or an empty release if the app has no releases yet.
+func (api API) GetLatestRelease(app model.App) (model.Release, error) {
+	getURL := fmt.Sprintf("%s/v0.1/apps/%s/%s/releases/latest", api.baseURL, app.Owner, app.AppName)
+
+	return api.getLatestRelease(getURL)
+}
+
+// GetLatestReleaseInGroup returns the latest release distributed to the group,
+// or an empty release if nothing was distributed to the group yet.
+func (api API) GetLatestReleaseInGroup(groupName string, app model.App) (model.Release, error) {
+	getURL := fmt.Sprintf("%s/v0.1/apps/%s/%s/distribution_groups/%s/releases/latest", api.baseURL, app.Owner, app.AppName, url.PathEscape(groupName))
+
+	return api.getLatestRelease(getURL)
+}
+
+func (api API) getLatestRelease(getURL string) (model.Release, error) {
+	var release model.Release
+
+	statusCode, err := api.Client.jsonRequest("get latest release", http.MethodGet, getURL, nil, &release)
+	if IsStatus(err, http.StatusNotFound) {
+		return model.Release{}, nil
+	}
+	if err != nil {
+		return model.Release{}, err
+	}
+
+	if statusCode != http.StatusOK {
+		return model.Release{}, fmt.Errorf("invalid status code: %d, url: %s, body: %v", statusCode, Redact(getURL), release)
+	}
+
+	return release, nil
+}
+
+// GetReleasesInGroup returns the releases distributed to the group
+func (api API) GetReleasesInGroup(groupName string, app model.App) ([]model.Release, error) {
+	var (
+		getURL      = fmt.Sprintf("%s/v0.1/apps/%s/%s/distribution_groups/%s/releases", api.baseURL, app.Owner, app.AppName, url.PathEscape(groupName))
+		getResponse []model.Release
+	)
+
+	statusCode, err := api.Client.jsonRequest("get releases in group", http.MethodGet, getURL, nil, &getResponse)
+	if err != nil {
+		return []model.Release{}, err
+	}
+
+	if statusCode != http.StatusOK {
+		return []model.Release{}, fmt.Errorf("invalid status code: %d, url: %s, body: %v", statusCode, Redact(getURL), getResponse)
+	}
+
+	return getResponse, nil
+}
+
 // GetGroupByName ...
 func (api API) GetGroupByName(groupName string, app model.App) (model.Group, error) {
 	var (
@@ -71,13 +211,13 @@ func (api API) GetGroupByName(groupName string, app model.App) (model.Group, err
 		getResponse model.Group
 	)
 
-	statusCode, err := api.Client.jsonRequest(http.MethodGet, getURL, nil, &getResponse)
+	statusCode, err := api.Client.jsonRequest("get group", http.MethodGet, getURL, nil, &getResponse)
 	if err != nil {
 		return model.Group{}, err
 	}
 
 	if statusCode != http.StatusOK {
-		return model.Group{}, fmt.Errorf("invalid status code: %d, url: %s, body: %v", statusCode, getURL, getResponse)
+		return model.Group{}, fmt.Errorf("invalid status code: %d, url: %s, body: %v", statusCode, Redact(getURL), getResponse)
 	}
 
 	return getResponse, err
@@ -90,13 +230,13 @@ func (api API) GetAllGroups(app model.App) ([]model.Group, error) {
 		getResponse []model.Group
 	)
 
-	statusCode, err := api.Client.jsonRequest(http.MethodGet, getURL, nil, &getResponse)
+	statusCode, err := api.Client.jsonRequest("get groups", http.MethodGet, getURL, nil, &getResponse)
 	if err != nil {
 		return []model.Group{}, err
 	}
 
 	if statusCode != http.StatusOK {
-		return []model.Group{}, fmt.Errorf("invalid status code: %d, url: %s, body: %v", statusCode, getURL, getResponse)
+		return []model.Group{}, fmt.Errorf("invalid status code: %d, url: %s, body: %v", statusCode, Redact(getURL), getResponse)
 	}
 
 	return getResponse, nil
@@ -109,13 +249,13 @@ func (api API) GetStore(storeName string, app model.App) (model.Store, error) {
 		getResponse model.Store
 	)
 
-	statusCode, err := api.Client.jsonRequest(http.MethodGet, getURL, nil, &getResponse)
+	statusCode, err := api.Client.jsonRequest("get store", http.MethodGet, getURL, nil, &getResponse)
 	if err != nil {
 		return model.Store{}, err
 	}
 
 	if statusCode != http.StatusOK {
-		return model.Store{}, fmt.Errorf("invalid status code: %d, url: %s, body: %v", statusCode, getURL, getResponse)
+		return model.Store{}, fmt.Errorf("invalid status code: %d, url: %s, body: %v", statusCode, Redact(getURL), getResponse)
 	}
 
 	return getResponse, nil
@@ -141,13 +281,17 @@ func (api API) AddReleaseToGroup(g model.Group, releaseID int, opts model.Releas
 		return err
 	}
 
-	statusCode, err := api.Client.jsonRequest(http.MethodPost, postURL, body, nil)
+	statusCode, err := api.Client.jsonRequest("add release to group", http.MethodPost, postURL, body, nil)
+	if IsStatus(err, http.StatusConflict) {
+		// The release is already there, for example a retried request was processed the first time.
+		return nil
+	}
 	if err != nil {
 		return err
 	}
 
 	if statusCode != http.StatusCreated {
-		return fmt.Errorf("invalid status code: %d, url: %s", statusCode, postURL)
+		return fmt.Errorf("invalid status code: %d, url: %s", statusCode, Redact(postURL))
 	}
 
 	return nil
@@ -169,13 +313,17 @@ func (api API) AddReleaseToStore(s model.Store, releaseID int, opts model.Releas
 		return err
 	}
 
-	statusCode, err := api.Client.jsonRequest(http.MethodPost, postURL, body, nil)
+	statusCode, err := api.Client.jsonRequest("add release to store", http.MethodPost, postURL, body, nil)
+	if IsStatus(err, http.StatusConflict) {
+		// The release is already there, for example a retried request was processed the first time.
+		return nil
+	}
 	if err != nil {
 		return err
 	}
 
 	if statusCode != http.StatusCreated {
-		return fmt.Errorf("invalid status code: %d, url: %s", statusCode, postURL)
+		return fmt.Errorf("invalid status code: %d, url: %s", statusCode, Redact(postURL))
 	}
 
 	return nil
@@ -201,13 +349,17 @@ func (api API) AddTesterToRelease(email string, releaseID int, opts model.Releas
 		return err
 	}
 
-	statusCode, err := api.Client.jsonRequest(http.MethodPost, postURL, body, nil)
+	statusCode, err := api.Client.jsonRequest("add tester to release", http.MethodPost, postURL, body, nil)
+	if IsStatus(err, http.StatusConflict) {
+		// The release is already there, for example a retried request was processed the first time.
+		return nil
+	}
 	if err != nil {
 		return err
 	}
 
 	if statusCode != http.StatusCreated {
-		return fmt.Errorf("invalid status code: %d, url: %s", statusCode, postURL)
+		return fmt.Errorf("invalid status code: %d, url: %s", statusCode, Redact(postURL))
 	}
 
 	return nil
@@ -229,13 +381,50 @@ func (api API) SetReleaseNoteOnRelease(releaseNote string, releaseID int, opts m
 		return err
 	}
 
-	statusCode, err := api.Client.jsonRequest(http.MethodPut, putURL, body, nil)
+	statusCode, err := api.Client.jsonRequest("set release notes", http.MethodPut, putURL, body, nil)
+	if err != nil {
+		return err
+	}
+
+	if statusCode != http.StatusOK {
+		return fmt.Errorf("invalid status code: %d, url: %s", statusCode, Redact(putURL))
+	}
+
+	return nil
+}
+
+// UpdateRelease ...
+func (api API) UpdateRelease(update model.ReleaseUpdate, releaseID int, opts model.ReleaseOptions) error {
+	patchURL := fmt.Sprintf("%s/v0.1/apps/%s/%s/releases/%d", api.baseURL, opts.App.Owner, opts.App.AppName, releaseID)
+
+	body, err := api.Client.MarshallContent(update)
+	if err != nil {
+		return err
+	}
+
+	statusCode, err := api.Client.jsonRequest("update release", http.MethodPatch, patchURL, body, nil)
 	if err != nil {
 		return err
 	}
 
 	if statusCode != http.StatusOK {
-		return fmt.Errorf("invalid status code: %d, url: %s", statusCode, putURL)
+		return fmt.Errorf("invalid status code: %d, url: %s", statusCode, Redact(patchURL))
+	}
+
+	return nil
+}
+
+// DeleteRelease ...
+func (api API) DeleteRelease(releaseID int, opts model.ReleaseOptions) error {
+	deleteURL := fmt.Sprintf("%s/v0.1/apps/%s/%s/releases/%d", api.baseURL, opts.App.Owner, opts.App.AppName, releaseID)
+
+	statusCode, err := api.Client.jsonRequest("delete release", http.MethodDelete, deleteURL, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	if statusCode != http.StatusOK {
+		return fmt.Errorf("invalid status code: %d, url: %s", statusCode, Redact(deleteURL))
 	}
 
 	return nil
@@ -274,23 +463,23 @@ func (api API) UploadSymbolToRelease(filePath string, release model.Release, opt
 		return err
 	}
 
-	statusCode, err := api.Client.jsonRequest(http.MethodPost, postURL, body, &postResponse)
+	statusCode, err := api.Client.jsonRequest("create symbol upload", http.MethodPost, postURL, body, &postResponse)
 	if err != nil {
 		return err
 	}
 
 	if statusCode != http.StatusOK {
-		return fmt.Errorf("invalid status code: %d, url: %s, body: %v", statusCode, postURL, postBody)
+		return fmt.Errorf("invalid status code: %d, url: %s, body: %v", statusCode, Redact(postURL), postBody)
 	}
 
 	// upload file to {upload_url}
-	statusCode, err = api.Client.uploadFile(postResponse.UploadURL, filePath)
+	statusCode, err = api.Client.uploadFile("upload symbol file", postResponse.UploadURL, filePath)
 	if err != nil {
 		return err
 	}
 
 	if statusCode != http.StatusCreated {
-		return fmt.Errorf("invalid status code: %d, url: %s", statusCode, postResponse.UploadURL)
+		return fmt.Errorf("invalid status code: %d, url: %s", statusCode, Redact(postResponse.UploadURL))
 	}
 
 	var (
@@ -305,13 +494,13 @@ func (api API) UploadSymbolToRelease(filePath string, release model.Release, opt
 		return err
 	}
 
-	statusCode, err = api.Client.jsonRequest(http.MethodPatch, patchURL, body, nil)
+	statusCode, err = api.Client.jsonRequest("commit symbol upload", http.MethodPatch, patchURL, body, nil)
 	if err != nil {
 		return err
 	}
 
 	if statusCode != http.StatusOK {
-		return fmt.Errorf("invalid status code: %d, url: %s", statusCode, patchURL)
+		return fmt.Errorf("invalid status code: %d, url: %s", statusCode, Redact(patchURL))
 	}
 
 	return nil
@@ -327,13 +516,13 @@ func (api API) CreateRelease(opts model.ReleaseOptions) (int, error) {
 		assetResponse fileAssetResponse
 	)
 
-	statusCode, err := api.Client.jsonRequest(http.MethodPost, assetsURL, nil, &assetResponse)
+	statusCode, err := api.Client.jsonRequest("create release upload", http.MethodPost, assetsURL, nil, &assetResponse)
 	if err != nil {
 		return releaseFailedID, err
 	}
 
 	if statusCode != http.StatusCreated {
-		return releaseFailedID, fmt.Errorf("invalid status code: %d, url: %s", statusCode, assetsURL)
+		return releaseFailedID, fmt.Errorf("invalid status code: %d, url: %s", statusCode, Redact(assetsURL))
 	}
 
 	fmt.Println("")
@@ -370,13 +559,13 @@ func (api API) CreateRelease(opts model.ReleaseOptions) (int, error) {
 		}
 	)
 
-	statusCode, err = api.Client.jsonRequest(http.MethodPost, metadataURL, nil, &metadataResponse)
+	statusCode, err = api.Client.jsonRequest("set upload metadata", http.MethodPost, metadataURL, nil, &metadataResponse)
 	if err != nil {
 		return releaseFailedID, err
 	}
 
 	if statusCode != http.StatusOK {
-		return releaseFailedID, fmt.Errorf("invalid status code: %d, url: %s", statusCode, metadataURL)
+		return releaseFailedID, fmt.Errorf("invalid status code: %d, url: %s", statusCode, Redact(metadataURL))
 	}
 
 	fmt.Println("")
@@ -405,13 +594,13 @@ func (api API) CreateRelease(opts model.ReleaseOptions) (int, error) {
 		finishedResponse interface{}
 	)
 
-	statusCode, err = api.Client.jsonRequest(http.MethodPost, uploadFinishedURL, nil, &finishedResponse)
+	statusCode, err = api.Client.jsonRequest("finish upload", http.MethodPost, uploadFinishedURL, nil, &finishedResponse)
 	if err != nil {
 		return releaseFailedID, err
 	}
 
 	if statusCode != http.StatusOK {
-		return releaseFailedID, fmt.Errorf("invalid status code: %d, url: %s", statusCode, uploadFinishedURL)
+		return releaseFailedID, fmt.Errorf("invalid status code: %d, url: %s", statusCode, Redact(uploadFinishedURL))
 	}
 
 	fmt.Println("")
@@ -438,13 +627,13 @@ func (api API) CreateRelease(opts model.ReleaseOptions) (int, error) {
 		return releaseFailedID, err
 	}
 
-	statusCode, err = api.Client.jsonRequest(http.MethodPatch, releasePatchURL, body, &releasePatchResponse)
+	statusCode, err = api.Client.jsonRequest("commit release upload", http.MethodPatch, releasePatchURL, body, &releasePatchResponse)
 	if err != nil {
 		return releaseFailedID, err
 	}
 
 	if statusCode != http.StatusOK {
-		return releaseFailedID, fmt.Errorf("invalid status code: %d, url: %s", statusCode, releasePatchURL)
+		return releaseFailedID, fmt.Errorf("invalid status code: %d, url: %s", statusCode, Redact(releasePatchURL))
 	}
 
 	fmt.Println("")
@@ -457,7 +646,7 @@ func (api API) CreateRelease(opts model.ReleaseOptions) (int, error) {
 	releaseDistinctID := releaseFailedID
 	attempts := 1
 
-	for !maxAttemptsReached(attempts) {
+	for !api.maxAttemptsReached(attempts) {
 		fmt.Println(fmt.Sprintf("Attempt(s): %d", attempts))
 
 		var (
@@ -473,13 +662,13 @@ func (api API) CreateRelease(opts model.ReleaseOptions) (int, error) {
 			}
 		)
 
-		statusCode, err = api.Client.jsonRequest(http.MethodGet, getURL, nil, &getResponse)
+		statusCode, err = api.Client.jsonRequest("get release upload status", http.MethodGet, getURL, nil, &getResponse)
 		if err != nil {
 			return releaseFailedID, err
 		}
 
 		if statusCode != http.StatusOK {
-			return releaseFailedID, fmt.Errorf("invalid status code: %d, url: %s", statusCode, getURL)
+			return releaseFailedID, fmt.Errorf("invalid status code: %d, url: %s", statusCode, Redact(getURL))
 		}
 
 		uploadStatus = getResponse.UploadStatus
@@ -494,6 +683,9 @@ func (api API) CreateRelease(opts model.ReleaseOptions) (int, error) {
 			break
 		} else {
 			attempts++
+			if api.maxAttemptsReached(attempts) {
+				return releaseFailedID, fmt.Errorf("release is not ready after %d upload status check(s), current status: %s", api.uploadStatusMaxAttempts, uploadStatus)
+			}
 
 			sleepDuration := generateRandomIntBetweenRange(5, 10)
 			fmt.Println(fmt.Sprintf("Waiting for %d second(s), current status: %s", sleepDuration, uploadStatus))
@@ -547,7 +739,7 @@ func (api API) uploadChunksInParallel(fileChunks [][]byte, chunkIDs []int, asset
 				}
 			)
 
-			statusCode, err := api.Client.jsonRequest(http.MethodPost, chunkUploadURL, chunk, &chunkUploadResponse)
+			statusCode, err := api.Client.jsonRequest("upload chunk", http.MethodPost, chunkUploadURL, chunk, &chunkUploadResponse)
 			if err != nil {
 				retErr = err
 
@@ -561,7 +753,7 @@ func (api API) uploadChunksInParallel(fileChunks [][]byte, chunkIDs []int, asset
 			}
 
 			if statusCode != http.StatusOK {
-				retErr = fmt.Errorf("invalid status code: %d, url: %s", statusCode, chunkUploadURL)
+				retErr = fmt.Errorf("invalid status code: %d, url: %s", statusCode, Redact(chunkUploadURL))
 				return
 			}
 
@@ -599,6 +791,6 @@ func uploadIsReadyForDeploy(status string) (bool, error) {
 	}
 }
 
-func maxAttemptsReached(current int) bool {
-	return current >= maxAttempts
+func (api API) maxAttemptsReached(current int) bool {
+	return current > api.uploadStatusMaxAttempts
 }
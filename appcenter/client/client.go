@@ -0,0 +1,248 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-utils/retry"
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+type roundTripper struct {
+	token   string
+	limiter *rateLimiter
+	// base sends the requests, http.DefaultTransport if nil.
+	base http.RoundTripper
+}
+
+// RoundTrip ...
+func (rt roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := rt.limiter.wait(req.Context()); err != nil {
+		return nil, err
+	}
+
+	req.Header.Set(
+		"x-api-token", rt.token,
+	)
+	req.Header.Set(
+		"content-type", "application/json; charset=utf-8",
+	)
+
+	base := rt.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Hold back the requests if the rate limit window is exhausted, before App Center starts throttling them.
+	if !isThrottled(resp) {
+		if wait, ok := retryAfter(resp, time.Now()); ok {
+			rt.limiter.pause(wait)
+		}
+	}
+
+	return resp, nil
+}
+
+// Client ...
+type Client struct {
+	httpClient *retryablehttp.Client
+	// uploadClient sends the data plane requests, see dataPlaneOperations.
+	uploadClient *retryablehttp.Client
+	limiter      *rateLimiter
+	throttle     *throttleStats
+}
+
+// NewClient returns an AppCenter authenticated client
+func NewClient(token string) Client {
+	c := Client{
+		limiter:  newRateLimiter(defaultRequestsPerSecond, defaultRequestBurst),
+		throttle: &throttleStats{},
+	}
+
+	AddSecret(token)
+
+	// Both clients send the requests through the same transport, so they share the rate limit.
+	rt := &roundTripper{
+		token:   token,
+		limiter: c.limiter,
+	}
+	c.httpClient = c.newRetryableClient(rt, DefaultAPIRetryPolicy())
+	c.uploadClient = c.newRetryableClient(rt, DefaultUploadRetryPolicy())
+
+	return c
+}
+
+func (c Client) newRetryableClient(rt *roundTripper, policy RetryPolicy) *retryablehttp.Client {
+	// The retry.NewHTTPClient returns the last response once the retries are exhausted, so its error body can be decoded.
+	retClient := retry.NewHTTPClient()
+	retClient.Logger = RedactingLogger{}
+	retClient.CheckRetry = c.checkRetry
+	retClient.Backoff = c.backoff
+	retClient.HTTPClient.Transport = rt
+	setRetryPolicy(retClient, policy)
+
+	return retClient
+}
+
+func setRetryPolicy(retClient *retryablehttp.Client, policy RetryPolicy) {
+	retClient.RetryMax = policy.MaxRetries
+	retClient.RetryWaitMin = policy.MinBackoff
+	retClient.RetryWaitMax = policy.MaxBackoff
+	retClient.HTTPClient.Timeout = policy.Timeout
+}
+
+// setTransport sets the transport the authenticated requests are sent with.
+func (c Client) setTransport(transport http.RoundTripper) {
+	if rt, ok := c.httpClient.HTTPClient.Transport.(*roundTripper); ok {
+		rt.base = transport
+	}
+}
+
+// setRetryPolicies sets the retry policy of the control plane and the data plane requests.
+func (c Client) setRetryPolicies(api, upload RetryPolicy) {
+	setRetryPolicy(c.httpClient, api)
+	setRetryPolicy(c.uploadClient, upload)
+}
+
+// clientFor returns the client sending the requests of the operation.
+func (c Client) clientFor(operation string) *retryablehttp.Client {
+	if dataPlaneOperations[operation] {
+		return c.uploadClient
+	}
+
+	return c.httpClient
+}
+
+// jsonRequest sends the request and decodes the response into response.
+// Error status codes are returned as an APIError named after the operation.
+// Returned errors are redacted, as they can contain the upload URLs and request dumps.
+func (c Client) jsonRequest(operation, method, url string, body []byte, response interface{}) (int, error) {
+	statusCode, err := c.doJSONRequest(operation, method, url, body, response)
+	return statusCode, redactError(err)
+}
+
+func (c Client) doJSONRequest(operation, method, url string, body []byte, response interface{}) (int, error) {
+	var reader io.Reader
+
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := retryablehttp.NewRequestWithContext(withRetrySafe(context.Background(), operation, method), method, url, reader)
+
+	if err != nil {
+		return -1, err
+	}
+
+	resp, err := c.clientFor(operation).Do(req)
+	if err != nil {
+		return -1, err
+	}
+
+	defer func() {
+		if resp != nil {
+			if err := resp.Body.Close(); err != nil {
+				log.Warnf("failed to close body: %s", err)
+			}
+		}
+	}()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		rb, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return resp.StatusCode, err
+		}
+
+		return resp.StatusCode, newAPIError(operation, resp, rb)
+	}
+
+	if resp != nil && response != nil {
+		rb, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return -1, err
+		}
+
+		if unmarshalErr := json.Unmarshal(rb, response); unmarshalErr != nil {
+			reqDump, err := httputil.DumpRequestOut(resp.Request, true)
+			if err != nil {
+				log.Warnf("failed to dump request: %v", redactError(err))
+			}
+
+			respDump, err := httputil.DumpResponse(resp, false)
+			if err != nil {
+				log.TWarnf("failed to dump response: %s", redactError(err))
+			}
+
+			return resp.StatusCode, fmt.Errorf("failed to unmarshal response: %s, request: %s, response headers: %s response body: %s", unmarshalErr, Redact(string(reqDump)), Redact(string(respDump)), Redact(string(rb)))
+		}
+	}
+
+	return resp.StatusCode, nil
+}
+
+// MarshallContent ...
+func (c Client) MarshallContent(content interface{}) ([]byte, error) {
+	b, err := json.Marshal(content)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	return b, err
+}
+
+// uploadFile uploads the file to the URL with a PUT request, returned errors are redacted.
+func (c Client) uploadFile(operation, url string, filePath string) (int, error) {
+	statusCode, err := c.doUploadFile(operation, url, filePath)
+	return statusCode, redactError(err)
+}
+
+func (c Client) doUploadFile(operation, url string, filePath string) (int, error) {
+	fb, err := os.ReadFile(filePath)
+	if err != nil {
+		return -1, err
+	}
+
+	uploadReq, err := retryablehttp.NewRequestWithContext(withRetrySafe(context.Background(), operation, http.MethodPut), http.MethodPut, url, bytes.NewReader(fb))
+	if err != nil {
+		return -1, err
+	}
+
+	uploadReq.Header.Set("x-ms-blob-type", "BlockBlob")
+	uploadReq.Header.Set("content-length", strconv.Itoa(len(fb)))
+
+	resp, err := c.clientFor(operation).Do(uploadReq)
+	if err != nil {
+		return -1, err
+	}
+
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Warnf("failed to close body: %s", err)
+		}
+	}()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		rb, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return resp.StatusCode, err
+		}
+
+		return resp.StatusCode, newAPIError(operation, resp, rb)
+	}
+
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,109 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/model"
+)
+
+// maxRawErrorBodyLength caps the message of errors whose body is not JSON, for example an HTML error page of a proxy.
+const maxRawErrorBodyLength = 512
+
+// requestIDHeaders are the response headers App Center and its upload and blob services report the request ID in.
+var requestIDHeaders = []string{"x-ms-request-id", "x-request-id", "x-correlation-id"}
+
+// APIError is returned when App Center responds to a call with an error status code.
+type APIError struct {
+	// Operation is the failed call, for example "get app details".
+	Operation string
+	Method    string
+	// Path is the path of the request URL, the query is left out as it can contain upload tokens.
+	Path       string
+	StatusCode int
+	Code       string
+	Message    string
+	RequestID  string
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("%s failed: %s %s returned %d", e.Operation, e.Method, e.Path, e.StatusCode)
+	if e.Code != "" {
+		msg += ", code: " + e.Code
+	}
+	if e.Message != "" {
+		msg += ", message: " + e.Message
+	}
+	if e.RequestID != "" {
+		msg += ", request ID: " + e.RequestID
+	}
+
+	return msg
+}
+
+// AsAPIError returns the APIError in the error's chain.
+func AsAPIError(err error) (*APIError, bool) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr, true
+	}
+
+	return nil, false
+}
+
+// IsStatus reports whether the error is an APIError with the given status code.
+func IsStatus(err error, statusCode int) bool {
+	apiErr, ok := AsAPIError(err)
+	return ok && apiErr.StatusCode == statusCode
+}
+
+// newAPIError decodes the error body of a failed call.
+// App Center returns the code and message either on the top level, or nested in an error object.
+func newAPIError(operation string, resp *http.Response, body []byte) *APIError {
+	apiErr := &APIError{
+		Operation:  operation,
+		StatusCode: resp.StatusCode,
+	}
+
+	if resp.Request != nil {
+		apiErr.Method = resp.Request.Method
+		apiErr.Path = resp.Request.URL.Path
+	}
+
+	for _, header := range requestIDHeaders {
+		if id := resp.Header.Get(header); id != "" {
+			apiErr.RequestID = id
+			break
+		}
+	}
+
+	var errorBody struct {
+		model.Error
+		Nested json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(body, &errorBody); err != nil {
+		apiErr.Message = strings.TrimSpace(Redact(string(body)))
+		if len(apiErr.Message) > maxRawErrorBodyLength {
+			apiErr.Message = apiErr.Message[:maxRawErrorBodyLength] + "..."
+		}
+		return apiErr
+	}
+
+	apiErr.Code = errorBody.Code
+	apiErr.Message = Redact(errorBody.Message)
+
+	var nested model.Error
+	if err := json.Unmarshal(errorBody.Nested, &nested); err == nil {
+		if nested.Code != "" {
+			apiErr.Code = nested.Code
+		}
+		if nested.Message != "" {
+			apiErr.Message = Redact(nested.Message)
+		}
+	}
+
+	return apiErr
+}
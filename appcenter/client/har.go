@@ -0,0 +1,317 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	harVersion     = "1.2"
+	harCreatorName = "steps-appcenter-deploy-android"
+	// maxHARBodySize is the largest JSON body recorded, larger and non-JSON bodies (like upload chunks) are elided.
+	maxHARBodySize = 1024 * 1024
+)
+
+// sensitiveHeaders are recorded with their value redacted.
+var sensitiveHeaders = map[string]bool{
+	"x-api-token":         true,
+	"authorization":       true,
+	"proxy-authorization": true,
+	"cookie":              true,
+	"set-cookie":          true,
+}
+
+// HAR 1.2 format, see http://www.softwareishard.com/blog/har-12-spec/
+type harFile struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string      `json:"version"`
+	Creator harCreator  `json:"creator"`
+	Entries []*harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	Comment         string      `json:"comment,omitempty"`
+
+	started time.Time
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+type harContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// HARRecorder is a transport recording every request and response, which can be written as a HAR 1.2 file.
+// URLs, headers and bodies are redacted, only JSON bodies are recorded.
+type HARRecorder struct {
+	base http.RoundTripper
+
+	mu      sync.Mutex
+	entries []*harEntry
+}
+
+// NewHARRecorder returns a recorder sending the requests with the base transport.
+func NewHARRecorder(base http.RoundTripper) *HARRecorder {
+	return &HARRecorder{base: base}
+}
+
+// RoundTrip sends the request with the base transport and records it.
+func (r *HARRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	entry := &harEntry{started: time.Now()}
+	entry.StartedDateTime = entry.started.Format(time.RFC3339Nano)
+
+	var reqBody []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		b, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		if err := req.Body.Close(); err != nil {
+			return nil, err
+		}
+
+		reqBody = b
+		req.Body = io.NopCloser(bytes.NewReader(b))
+	}
+
+	entry.Request = newHARRequest(req, reqBody)
+
+	resp, err := r.base.RoundTrip(req)
+	wait := time.Since(entry.started)
+	if err != nil {
+		entry.Time = milliseconds(wait)
+		entry.Timings = harTimings{Wait: milliseconds(wait)}
+		entry.Comment = Redact(err.Error())
+		r.add(entry)
+
+		return nil, err
+	}
+
+	receiveStart := time.Now()
+	respBody, readErr := io.ReadAll(resp.Body)
+	if err := resp.Body.Close(); err != nil && readErr == nil {
+		readErr = err
+	}
+	receive := time.Since(receiveStart)
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	entry.Response = newHARResponse(resp, respBody)
+	entry.Timings = harTimings{Wait: milliseconds(wait), Receive: milliseconds(receive)}
+	entry.Time = entry.Timings.Wait + entry.Timings.Receive
+	if readErr != nil {
+		entry.Comment = Redact(readErr.Error())
+	}
+	r.add(entry)
+
+	return resp, readErr
+}
+
+func (r *HARRecorder) add(entry *harEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries = append(r.entries, entry)
+}
+
+// Write writes the recorded requests to path as a HAR 1.2 file, in the order they were started.
+func (r *HARRecorder) Write(path string) error {
+	r.mu.Lock()
+	entries := make([]*harEntry, len(r.entries))
+	copy(entries, r.entries)
+	r.mu.Unlock()
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].started.Before(entries[j].started)
+	})
+
+	b, err := json.MarshalIndent(harFile{
+		Log: harLog{
+			Version: harVersion,
+			Creator: harCreator{Name: harCreatorName, Version: creatorVersion()},
+			Entries: entries,
+		},
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, b, 0644)
+}
+
+func newHARRequest(req *http.Request, body []byte) harRequest {
+	redactedURL := Redact(req.URL.String())
+
+	request := harRequest{
+		Method:      req.Method,
+		URL:         redactedURL,
+		HTTPVersion: req.Proto,
+		Cookies:     []harNameValue{},
+		Headers:     harHeaders(req.Header),
+		QueryString: []harNameValue{},
+		HeadersSize: -1,
+		BodySize:    int64(len(body)),
+	}
+
+	if parsed, err := url.Parse(redactedURL); err == nil {
+		for _, key := range sortedQueryKeys(parsed.Query()) {
+			for _, value := range parsed.Query()[key] {
+				request.QueryString = append(request.QueryString, harNameValue{Name: key, Value: value})
+			}
+		}
+	}
+
+	if len(body) > 0 {
+		text, comment := harBody(body)
+		request.PostData = &harPostData{
+			MimeType: req.Header.Get("Content-Type"),
+			Text:     text,
+			Comment:  comment,
+		}
+	}
+
+	return request
+}
+
+func newHARResponse(resp *http.Response, body []byte) harResponse {
+	text, comment := harBody(body)
+
+	return harResponse{
+		Status:      resp.StatusCode,
+		StatusText:  http.StatusText(resp.StatusCode),
+		HTTPVersion: resp.Proto,
+		Cookies:     []harNameValue{},
+		Headers:     harHeaders(resp.Header),
+		Content: harContent{
+			Size:     int64(len(body)),
+			MimeType: resp.Header.Get("Content-Type"),
+			Text:     text,
+			Comment:  comment,
+		},
+		HeadersSize: -1,
+		BodySize:    int64(len(body)),
+	}
+}
+
+// harBody returns the redacted text of a JSON body, or a comment why the body is left out.
+func harBody(body []byte) (string, string) {
+	switch {
+	case len(body) == 0:
+		return "", ""
+	case len(body) > maxHARBodySize:
+		return "", fmt.Sprintf("%d bytes elided", len(body))
+	case !json.Valid(body):
+		return "", fmt.Sprintf("%d bytes of non-JSON content elided", len(body))
+	default:
+		return Redact(string(body)), ""
+	}
+}
+
+func harHeaders(header http.Header) []harNameValue {
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	headers := []harNameValue{}
+	for _, key := range keys {
+		for _, value := range header[key] {
+			if sensitiveHeaders[strings.ToLower(key)] {
+				value = redacted
+			}
+
+			headers = append(headers, harNameValue{Name: key, Value: Redact(value)})
+		}
+	}
+
+	return headers
+}
+
+func sortedQueryKeys(query url.Values) []string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// creatorVersion returns the module version of the binary, "(devel)" if it was built from a checkout.
+func creatorVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+
+	return "unknown"
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
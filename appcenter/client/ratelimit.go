@@ -0,0 +1,223 @@
+package client
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+const (
+	defaultRequestsPerSecond = 10
+	defaultRequestBurst      = 10
+	// maxThrottleWait caps how long a single Retry-After or rate limit reset can hold back the requests.
+	maxThrottleWait = 2 * time.Minute
+)
+
+// safePostOperations are the POST calls which can be sent again if an earlier attempt might have been processed:
+// chunk uploads are keyed by their block number, the upload calls by the upload's token,
+// and adding a release to a destination it is already in results in a conflict, which is treated as success.
+// Other POSTs, like creating a release upload, would create a second resource, so they are not retried
+// unless App Center rejected the earlier attempt with a throttling response.
+var safePostOperations = map[string]bool{
+	"set upload metadata":   true,
+	"upload chunk":          true,
+	"finish upload":         true,
+	"add release to group":  true,
+	"add release to store":  true,
+	"add tester to release": true,
+}
+
+type retrySafeKey struct{}
+
+// withRetrySafe marks whether the request can be retried after a server error or a connection failure.
+func withRetrySafe(ctx context.Context, operation, method string) context.Context {
+	safe := method != http.MethodPost || safePostOperations[operation]
+	return context.WithValue(ctx, retrySafeKey{}, safe)
+}
+
+func isRetrySafe(ctx context.Context) bool {
+	safe, ok := ctx.Value(retrySafeKey{}).(bool)
+	return !ok || safe
+}
+
+// rateLimiter is a token bucket shared by every request of a client, so the parallel chunk uploads
+// and distribution calls stay under App Center's rate limit together.
+// When App Center throttles a request, the whole bucket is paused until the time it asked for.
+type rateLimiter struct {
+	mu          sync.Mutex
+	rate        float64
+	burst       float64
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+}
+
+func newRateLimiter(requestsPerSecond float64, burst int) *rateLimiter {
+	return &rateLimiter{
+		rate:   requestsPerSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// wait blocks until a request can be sent or the context is done.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	for {
+		delay := l.reserve()
+		if delay <= 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reserve takes a token and returns zero, or returns how long to wait before trying again.
+func (l *rateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Before(l.pausedUntil) {
+		return l.pausedUntil.Sub(now)
+	}
+
+	l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+
+	return time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+}
+
+// pause holds back every request for the given duration.
+func (l *rateLimiter) pause(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if until := time.Now().Add(d); until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+}
+
+// throttleStats counts how many times and for how long App Center throttled the client.
+type throttleStats struct {
+	mu     sync.Mutex
+	events int
+	wait   time.Duration
+}
+
+func (s *throttleStats) record(wait time.Duration) (int, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.events++
+	s.wait += wait
+
+	return s.events, s.wait
+}
+
+func isThrottled(resp *http.Response) bool {
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable
+}
+
+// retryAfter returns how long App Center asked the client to wait: the Retry-After header (in seconds or as an HTTP date),
+// or the reset of an exhausted rate limit window.
+func retryAfter(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if value := resp.Header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil {
+			return capThrottleWait(time.Duration(seconds) * time.Second), true
+		}
+		if date, err := http.ParseTime(value); err == nil {
+			return capThrottleWait(date.Sub(now)), true
+		}
+	}
+
+	for _, prefix := range []string{"X-RateLimit-", "RateLimit-"} {
+		if resp.Header.Get(prefix+"Remaining") != "0" {
+			continue
+		}
+
+		reset, err := strconv.ParseInt(resp.Header.Get(prefix+"Reset"), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		// The reset is either a Unix timestamp or the number of seconds until the window resets.
+		if reset > 1_000_000_000 {
+			return capThrottleWait(time.Unix(reset, 0).Sub(now)), true
+		}
+		return capThrottleWait(time.Duration(reset) * time.Second), true
+	}
+
+	return 0, false
+}
+
+func capThrottleWait(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	if d > maxThrottleWait {
+		return maxThrottleWait
+	}
+
+	return d
+}
+
+// checkRetry retries throttled requests, and server errors and connection failures of requests which are safe to send again.
+func (c Client) checkRetry(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+
+	if err == nil && isThrottled(resp) {
+		return true, nil
+	}
+
+	retry, policyErr := retryablehttp.DefaultRetryPolicy(ctx, resp, err)
+	if !retry || policyErr != nil {
+		return retry, policyErr
+	}
+
+	return isRetrySafe(ctx), nil
+}
+
+// backoff waits as long as App Center asked for on throttling responses and pauses every other request meanwhile,
+// other failures are retried with exponential backoff.
+func (c Client) backoff(min, max time.Duration, attemptNum int, resp *http.Response) time.Duration {
+	if resp == nil || !isThrottled(resp) {
+		return retryablehttp.DefaultBackoff(min, max, attemptNum, resp)
+	}
+
+	wait, ok := retryAfter(resp, time.Now())
+	if !ok {
+		wait = retryablehttp.DefaultBackoff(min, max, attemptNum, resp)
+	}
+
+	c.limiter.pause(wait)
+	events, total := c.throttle.record(wait)
+
+	var path string
+	if resp.Request != nil {
+		path = resp.Request.URL.Path
+	}
+	log.Warnf("App Center throttled the request (%d %s), waiting %s (throttled %d time(s), %s in total)", resp.StatusCode, path, wait, events, total)
+
+	return wait
+}
@@ -0,0 +1,263 @@
+package client
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/fake"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/model"
+)
+
+var throttleTestApp = model.App{Owner: "owner", AppName: "app", AppType: model.AppTypeAndroid}
+
+// newThrottleTestAPI returns an API of the fake server retrying the requests twice, without backoff between other failures.
+func newThrottleTestAPI(t *testing.T, server *fake.Server) API {
+	policy := RetryPolicy{MaxRetries: 2, Timeout: 10 * time.Second}
+
+	api, err := CreateAPIWithClientParams(fake.Token, WithBaseURL(server.URL), WithRetryPolicies(policy, policy))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return api
+}
+
+// requestCount returns the number of requests received with the method and a path matching the regular expression.
+func requestCount(server *fake.Server, method, path string) int {
+	pattern := regexp.MustCompile(path)
+
+	count := 0
+	for _, request := range server.Requests() {
+		if request.Method == method && pattern.MatchString(request.Path) {
+			count++
+		}
+	}
+
+	return count
+}
+
+func TestThrottling(t *testing.T) {
+	const appPath = "^/v0.1/apps/owner/app$"
+
+	tests := []struct {
+		name  string
+		setup func(server *fake.Server)
+		// wantRequests is the number of app details requests sent by the two calls.
+		wantRequests int
+		// wantWait is the minimum time the two calls take.
+		wantWait time.Duration
+	}{
+		{
+			name:         "Retry-After in seconds",
+			setup:        func(server *fake.Server) { server.Throttle(http.MethodGet, appPath, time.Second, 1) },
+			wantRequests: 3,
+			wantWait:     time.Second,
+		},
+		{
+			name: "Retry-After as an HTTP date",
+			setup: func(server *fake.Server) {
+				// The date has a second resolution, so it is at least a second away.
+				retryAt := time.Now().Add(2 * time.Second).UTC().Format(http.TimeFormat)
+				server.AddRule(fake.Rule{Method: http.MethodGet, Path: appPath, Status: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {retryAt}}, Times: 1})
+			},
+			wantRequests: 3,
+			wantWait:     time.Second,
+		},
+		{
+			name: "X-RateLimit reset of a throttled request",
+			setup: func(server *fake.Server) {
+				server.AddRule(fake.Rule{Method: http.MethodGet, Path: appPath, Status: http.StatusServiceUnavailable, Header: http.Header{"X-Ratelimit-Remaining": {"0"}, "X-Ratelimit-Reset": {"1"}}, Times: 1})
+			},
+			wantRequests: 3,
+			wantWait:     time.Second,
+		},
+		{
+			name: "exhausted X-RateLimit window holds back the next request",
+			setup: func(server *fake.Server) {
+				server.AddRule(fake.Rule{Method: http.MethodGet, Path: appPath, Status: http.StatusOK, Body: `{"name":"app"}`, Header: http.Header{"X-Ratelimit-Remaining": {"0"}, "X-Ratelimit-Reset": {"1"}}, Times: 1})
+			},
+			wantRequests: 2,
+			wantWait:     time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := fake.NewServer()
+			defer server.Close()
+
+			server.AddApp("owner", "app")
+			tt.setup(server)
+
+			api := newThrottleTestAPI(t, server)
+
+			start := time.Now()
+			for i := 0; i < 2; i++ {
+				if _, err := api.GetAppDetails(throttleTestApp); err != nil {
+					t.Fatalf("GetAppDetails() error = %v", err)
+				}
+			}
+
+			if elapsed := time.Since(start); elapsed < tt.wantWait-50*time.Millisecond {
+				t.Errorf("calls took %s, want at least %s", elapsed, tt.wantWait)
+			}
+			if got := requestCount(server, http.MethodGet, appPath); got != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestThrottling_sharedTokenBucket(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	server.AddApp("owner", "app")
+	server.Throttle(http.MethodGet, "^/v0.1/apps/owner/app$", time.Second, 1)
+
+	api := newThrottleTestAPI(t, server)
+
+	start := time.Now()
+	var groupsDone time.Duration
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		if _, err := api.GetAppDetails(throttleTestApp); err != nil {
+			t.Errorf("GetAppDetails() error = %v", err)
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+		// Started after the throttled request, which pauses every request of the client.
+		for requestCount(server, http.MethodGet, "^/v0.1/apps/owner/app$") == 0 {
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		if _, err := api.GetAllGroups(throttleTestApp); err != nil {
+			t.Errorf("GetAllGroups() error = %v", err)
+		}
+		groupsDone = time.Since(start)
+	}()
+
+	wg.Wait()
+
+	if groupsDone < 900*time.Millisecond {
+		t.Errorf("unthrottled request finished after %s, want it held back by the throttled one for about 1s", groupsDone)
+	}
+}
+
+func Test_rateLimiter(t *testing.T) {
+	limiter := newRateLimiter(10, 2)
+
+	for i := 0; i < 2; i++ {
+		if delay := limiter.reserve(); delay != 0 {
+			t.Fatalf("reserve() #%d = %s, want 0 within the burst", i+1, delay)
+		}
+	}
+
+	if delay := limiter.reserve(); delay <= 0 || delay > 100*time.Millisecond {
+		t.Errorf("reserve() = %s, want a wait of up to 100ms for the next token", delay)
+	}
+
+	limiter.pause(time.Minute)
+	if delay := limiter.reserve(); delay < 59*time.Second {
+		t.Errorf("reserve() = %s, want about a minute while paused", delay)
+	}
+
+	// A shorter pause does not cut the longer one.
+	limiter.pause(time.Second)
+	if delay := limiter.reserve(); delay < 59*time.Second {
+		t.Errorf("reserve() = %s, want the longer pause to be kept", delay)
+	}
+}
+
+func TestRetry_onlySafePosts(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		path   string
+		call   func(t *testing.T, api API) error
+		// wantRequests is the number of the failing POST requests sent.
+		wantRequests int
+		wantErr      bool
+	}{
+		{
+			name:         "server error of creating an upload is not retried",
+			status:       http.StatusInternalServerError,
+			path:         "^/v0.1/apps/owner/app/uploads/releases$",
+			call:         createRelease,
+			wantRequests: 1,
+			wantErr:      true,
+		},
+		{
+			name:         "throttled upload creation is retried",
+			status:       http.StatusTooManyRequests,
+			path:         "^/v0.1/apps/owner/app/uploads/releases$",
+			call:         createRelease,
+			wantRequests: 2,
+		},
+		{
+			name:         "server error of a chunk upload is retried",
+			status:       http.StatusInternalServerError,
+			path:         "^/upload/upload_chunk/",
+			call:         createRelease,
+			wantRequests: 2,
+		},
+		{
+			name:   "server error of adding a release to a group is retried",
+			status: http.StatusInternalServerError,
+			path:   "^/v0.1/apps/owner/app/releases/1/groups$",
+			call: func(t *testing.T, api API) error {
+				return api.AddReleaseToGroup(model.Group{ID: "group-Beta"}, 1, model.ReleaseOptions{App: throttleTestApp})
+			},
+			wantRequests: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := fake.NewServer()
+			defer server.Close()
+
+			app := server.AddApp("owner", "app")
+			app.AddGroup("Beta", false)
+			app.Releases = append(app.Releases, &fake.Release{Release: model.Release{ID: 1, Enabled: true}})
+
+			if tt.status == http.StatusTooManyRequests {
+				server.Throttle(http.MethodPost, tt.path, 0, 1)
+			} else {
+				server.Fail(http.MethodPost, tt.path, tt.status, 1)
+			}
+
+			err := tt.call(t, newThrottleTestAPI(t, server))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got := requestCount(server, http.MethodPost, tt.path); got != tt.wantRequests {
+				t.Errorf("POST %s requests = %d, want %d", tt.path, got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func createRelease(t *testing.T, api API) error {
+	path := filepath.Join(t.TempDir(), "app.apk")
+	if err := os.WriteFile(path, []byte(strings.Repeat("content", 128)), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	releaseID, err := api.CreateRelease(model.ReleaseOptions{App: throttleTestApp, FilePath: path})
+	if err == nil && releaseID == 0 {
+		t.Errorf("CreateRelease() = %d, want a release", releaseID)
+	}
+
+	return err
+}
@@ -0,0 +1,83 @@
+package client
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+const redacted = "[REDACTED]"
+
+var (
+	// tokenHeaderPattern matches the API token header of request dumps.
+	tokenHeaderPattern = regexp.MustCompile(`(?i)(x-api-token:[ \t]*)[^\r\n]+`)
+	// tokenQueryPattern matches the upload token query parameters and the signature of SAS URLs.
+	tokenQueryPattern = regexp.MustCompile(`(?i)([?&](?:token|url_encoded_token|sig)=)[^&\s"'<>]+`)
+	// tokenJSONPattern matches the upload tokens of the create upload response body.
+	tokenJSONPattern = regexp.MustCompile(`(?i)("(?:token|url_encoded_token)"\s*:\s*")[^"]*`)
+)
+
+var secrets = struct {
+	sync.RWMutex
+	values []string
+}{}
+
+// AddSecret registers a value which is masked wherever Redact finds it, for example the API token.
+func AddSecret(value string) {
+	if strings.TrimSpace(value) == "" {
+		return
+	}
+
+	secrets.Lock()
+	defer secrets.Unlock()
+
+	secrets.values = append(secrets.values, value)
+}
+
+// Redact masks the API token header, the upload token query parameters, SAS signatures and the registered secrets.
+func Redact(s string) string {
+	s = tokenHeaderPattern.ReplaceAllString(s, "${1}"+redacted)
+	s = tokenQueryPattern.ReplaceAllString(s, "${1}"+redacted)
+	s = tokenJSONPattern.ReplaceAllString(s, "${1}"+redacted)
+
+	secrets.RLock()
+	defer secrets.RUnlock()
+
+	for _, value := range secrets.values {
+		s = strings.ReplaceAll(s, value, redacted)
+	}
+
+	return s
+}
+
+// redactedError redacts the message of an error, while errors.As and errors.Is still see the original one.
+type redactedError struct {
+	err error
+}
+
+func (e redactedError) Error() string {
+	return Redact(e.err.Error())
+}
+
+func (e redactedError) Unwrap() error {
+	return e.err
+}
+
+func redactError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return redactedError{err: err}
+}
+
+// RedactingLogger logs the messages of a retryablehttp client, which contain the request URLs, as redacted debug logs.
+type RedactingLogger struct{}
+
+// Printf implements the retryablehttp.Logger interface.
+func (RedactingLogger) Printf(format string, args ...interface{}) {
+	log.Debugf("%s", Redact(fmt.Sprintf(format, args...)))
+}
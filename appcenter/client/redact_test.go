@@ -0,0 +1,185 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/fake"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/model"
+)
+
+func TestRedact(t *testing.T) {
+	AddSecret("webhook-secret-4711")
+	AddSecret("  ")
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "x-api-token header",
+			in:   "GET /v0.1/apps HTTP/1.1\r\nX-Api-Token: 0123abcd\r\nAccept: application/json\r\n",
+			want: "GET /v0.1/apps HTTP/1.1\r\nX-Api-Token: [REDACTED]\r\nAccept: application/json\r\n",
+		},
+		{
+			name: "x-api-token header in lower case",
+			in:   "x-api-token:0123abcd",
+			want: "x-api-token:[REDACTED]",
+		},
+		{
+			name: "token query parameter",
+			in:   "https://upload.appcenter.ms/upload/upload_chunk/abc?token=secret123&block_number=1",
+			want: "https://upload.appcenter.ms/upload/upload_chunk/abc?token=[REDACTED]&block_number=1",
+		},
+		{
+			name: "url_encoded_token query parameter",
+			in:   "https://upload.appcenter.ms/upload/set_metadata/abc?file_name=app.apk&url_encoded_token=a%2Bb%3D",
+			want: "https://upload.appcenter.ms/upload/set_metadata/abc?file_name=app.apk&url_encoded_token=[REDACTED]",
+		},
+		{
+			name: "SAS signature",
+			in:   `upload to "https://blob.core.windows.net/symbols/abc?sv=2019&sig=s3cr3t%2F&se=2030"`,
+			want: `upload to "https://blob.core.windows.net/symbols/abc?sv=2019&sig=[REDACTED]&se=2030"`,
+		},
+		{
+			name: "similar query parameters are kept",
+			in:   "https://api.appcenter.ms/v0.1/apps?tokens=1&id_token=2&signature=3",
+			want: "https://api.appcenter.ms/v0.1/apps?tokens=1&id_token=2&signature=3",
+		},
+		{
+			name: "JSON token body",
+			in:   `{"id":"abc","token":"secret123","url_encoded_token":"a%2Bb","upload_domain":"https://upload.appcenter.ms"}`,
+			want: `{"id":"abc","token":"[REDACTED]","url_encoded_token":"[REDACTED]","upload_domain":"https://upload.appcenter.ms"}`,
+		},
+		{
+			name: "indented JSON token body",
+			in:   "{\n  \"token\": \"secret123\"\n}",
+			want: "{\n  \"token\": \"[REDACTED]\"\n}",
+		},
+		{
+			name: "registered secret",
+			in:   "failed to call https://hooks.example.com/webhook-secret-4711/notify",
+			want: "failed to call https://hooks.example.com/[REDACTED]/notify",
+		},
+		{
+			name: "nothing to redact",
+			in:   "GET /v0.1/apps/owner/app returned 404",
+			want: "GET /v0.1/apps/owner/app returned 404",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Redact(tt.in); got != tt.want {
+				t.Errorf("Redact() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedact_dumps(t *testing.T) {
+	secrets := []string{"api-token-1234", "upload-token-5678", "sas-signature-90"}
+
+	req, err := http.NewRequest(http.MethodPost, "https://upload.appcenter.ms/upload/finished/abc?token=upload-token-5678", strings.NewReader(`{"token":"upload-token-5678"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("x-api-token", "api-token-1234")
+
+	reqDump, err := httputil.DumpRequestOut(req, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp := &http.Response{
+		Status:     "201 Created",
+		StatusCode: http.StatusCreated,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header: http.Header{
+			"Location":    {"https://blob.core.windows.net/symbols/abc?sv=2019&sig=sas-signature-90"},
+			"X-Api-Token": {"api-token-1234"},
+		},
+		Body: io.NopCloser(bytes.NewBufferString(`{"token":"upload-token-5678","url_encoded_token":"upload-token-5678"}`)),
+	}
+
+	respDump, err := httputil.DumpResponse(resp, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, dump := range map[string]string{"request": string(reqDump), "response": string(respDump)} {
+		got := Redact(dump)
+		for _, secret := range secrets {
+			if strings.Contains(got, secret) {
+				t.Errorf("Redact() of the %s dump contains %q:\n%s", name, secret, got)
+			}
+		}
+		if !strings.Contains(got, redacted) {
+			t.Errorf("Redact() of the %s dump = %q, want redacted values", name, got)
+		}
+	}
+}
+
+func TestRedact_unmarshalError(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	server.AddApp("owner", "app")
+	server.AddRule(fake.Rule{Method: http.MethodGet, Path: "^/v0.1/apps/owner/app$", Status: http.StatusOK, Body: `{"id": "owner-app", "token": "leaked-upload-token"`})
+
+	api, err := CreateAPIWithClientParams(fake.Token, WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = api.GetAppDetails(model.App{Owner: "owner", AppName: "app"})
+	if err == nil {
+		t.Fatal("GetAppDetails() error = nil, want an unmarshal error")
+	}
+	for _, secret := range []string{fake.Token, "leaked-upload-token"} {
+		if strings.Contains(err.Error(), secret) {
+			t.Errorf("GetAppDetails() error contains %q: %s", secret, err)
+		}
+	}
+	if !strings.Contains(err.Error(), "request: ") || !strings.Contains(err.Error(), redacted) {
+		t.Errorf("GetAppDetails() error = %s, want the redacted request and response", err)
+	}
+}
+
+func Test_redactError(t *testing.T) {
+	if err := redactError(nil); err != nil {
+		t.Errorf("redactError(nil) = %v, want nil", err)
+	}
+
+	apiErr := &APIError{Operation: "upload chunk", Method: http.MethodPost, Path: "/upload/upload_chunk/abc", StatusCode: http.StatusNotFound}
+	sentinel := errors.New("sentinel")
+	err := redactError(fmt.Errorf("https://upload.appcenter.ms/upload/upload_chunk/abc?token=secret123: %w", fmt.Errorf("%w, %v", sentinel, apiErr)))
+
+	if strings.Contains(err.Error(), "secret123") || !strings.Contains(err.Error(), "token="+redacted) {
+		t.Errorf("Error() = %q, want the token redacted", err.Error())
+	}
+
+	var unwrapped redactedError
+	if !errors.As(err, &unwrapped) {
+		t.Fatalf("errors.As() = false, want a redactedError")
+	}
+	if !errors.Is(err, sentinel) {
+		t.Errorf("errors.Is() = false, want the wrapped error")
+	}
+	if !strings.Contains(errors.Unwrap(err).Error(), "secret123") {
+		t.Errorf("Unwrap() = %v, want the original error", errors.Unwrap(err))
+	}
+
+	wrappedAPIErr := redactError(fmt.Errorf("failed: %w", apiErr))
+	if got, ok := AsAPIError(wrappedAPIErr); !ok || got != apiErr {
+		t.Errorf("AsAPIError() = %v, %t, want the wrapped APIError", got, ok)
+	}
+}
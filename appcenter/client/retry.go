@@ -0,0 +1,69 @@
+package client
+
+import (
+	"fmt"
+	"time"
+)
+
+// dataPlaneOperations are the calls sending the release binary and the symbols, they use the upload retry policy.
+var dataPlaneOperations = map[string]bool{
+	"upload chunk":       true,
+	"upload symbol file": true,
+}
+
+// RetryPolicy configures how many times and how patiently a failed request is retried.
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt.
+	MaxRetries int
+	// MinBackoff and MaxBackoff bound the exponential backoff between the attempts,
+	// throttling responses are waited out as long as App Center asks for instead.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// Timeout limits a single attempt, including reading the response body, zero means no limit.
+	Timeout time.Duration
+}
+
+// DefaultAPIRetryPolicy is the retry policy of the control plane JSON calls.
+func DefaultAPIRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: 4,
+		MinBackoff: time.Second,
+		MaxBackoff: 30 * time.Second,
+		Timeout:    time.Minute,
+	}
+}
+
+// DefaultUploadRetryPolicy is the retry policy of the data plane chunk and symbol uploads.
+func DefaultUploadRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: 4,
+		MinBackoff: time.Second,
+		MaxBackoff: 30 * time.Second,
+		Timeout:    5 * time.Minute,
+	}
+}
+
+// Validate checks the policy is consistent.
+func (p RetryPolicy) Validate() error {
+	if p.MaxRetries < 0 {
+		return fmt.Errorf("max retries has to be at least 0, got: %d", p.MaxRetries)
+	}
+	if p.MinBackoff < 0 || p.MaxBackoff < 0 || p.Timeout < 0 {
+		return fmt.Errorf("backoff and timeout can not be negative")
+	}
+	if p.MinBackoff > p.MaxBackoff {
+		return fmt.Errorf("min backoff (%s) is greater than max backoff (%s)", p.MinBackoff, p.MaxBackoff)
+	}
+
+	return nil
+}
+
+// String describes the policy for the logs.
+func (p RetryPolicy) String() string {
+	timeout := "none"
+	if p.Timeout > 0 {
+		timeout = p.Timeout.String()
+	}
+
+	return fmt.Sprintf("max retries: %d, backoff: %s-%s, timeout per attempt: %s", p.MaxRetries, p.MinBackoff, p.MaxBackoff, timeout)
+}
@@ -0,0 +1,194 @@
+package client_test
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/client"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/fake"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/model"
+)
+
+// retries returns a policy retrying maxRetries times without waiting in between.
+func retries(maxRetries int) client.RetryPolicy {
+	return client.RetryPolicy{MaxRetries: maxRetries, Timeout: 10 * time.Second}
+}
+
+// countRequests returns the number of requests received with the method and a path matching the regular expression.
+func countRequests(server *fake.Server, method, path string) int {
+	pattern := regexp.MustCompile(path)
+
+	count := 0
+	for _, request := range server.Requests() {
+		if request.Method == method && pattern.MatchString(request.Path) {
+			count++
+		}
+	}
+
+	return count
+}
+
+func writeFile(t *testing.T, name string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(strings.Repeat("content", 128)), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestRetryPolicies(t *testing.T) {
+	app := model.App{Owner: "owner", AppName: "app", AppType: model.AppTypeAndroid}
+
+	tests := []struct {
+		name string
+		// apiRetries and uploadRetries are the max retries of the API and the upload policy.
+		apiRetries    int
+		uploadRetries int
+		// failMethod and failPath fail failTimes requests (every request if 0) with a 500.
+		failMethod string
+		failPath   string
+		failTimes  int
+		call       func(t *testing.T, api client.API) error
+		wantErr    bool
+		// wantRequests is the number of the failing requests sent.
+		wantRequests int
+	}{
+		{
+			name:       "API call succeeds after the retries",
+			apiRetries: 2,
+			failMethod: http.MethodGet, failPath: "^/v0.1/apps/owner/app$", failTimes: 2,
+			call: func(t *testing.T, api client.API) error {
+				_, err := api.GetAppDetails(app)
+				return err
+			},
+			wantRequests: 3,
+		},
+		{
+			name:       "API call fails once the retries are exhausted",
+			apiRetries: 2,
+			failMethod: http.MethodGet, failPath: "^/v0.1/apps/owner/app$",
+			call: func(t *testing.T, api client.API) error {
+				_, err := api.GetAppDetails(app)
+				return err
+			},
+			wantErr:      true,
+			wantRequests: 3,
+		},
+		{
+			name:          "API call is not retried without API retries",
+			uploadRetries: 4,
+			failMethod:    http.MethodGet, failPath: "^/v0.1/apps/owner/app/distribution_groups$",
+			call: func(t *testing.T, api client.API) error {
+				_, err := api.GetAllGroups(app)
+				return err
+			},
+			wantErr:      true,
+			wantRequests: 1,
+		},
+		{
+			name:          "chunk upload uses the upload policy",
+			uploadRetries: 3,
+			failMethod:    http.MethodPost, failPath: "^/upload/upload_chunk/", failTimes: 3,
+			call: func(t *testing.T, api client.API) error {
+				_, err := api.CreateRelease(model.ReleaseOptions{App: app, FilePath: writeFile(t, "app.apk")})
+				return err
+			},
+			wantRequests: 4,
+		},
+		{
+			name:          "chunk upload fails once the upload retries are exhausted",
+			apiRetries:    4,
+			uploadRetries: 1,
+			failMethod:    http.MethodPost, failPath: "^/upload/upload_chunk/",
+			call: func(t *testing.T, api client.API) error {
+				_, err := api.CreateRelease(model.ReleaseOptions{App: app, FilePath: writeFile(t, "app.apk")})
+				return err
+			},
+			wantErr:      true,
+			wantRequests: 2,
+		},
+		{
+			name:          "symbol upload uses the upload policy",
+			uploadRetries: 2,
+			failMethod:    http.MethodPut, failPath: "^/blob/symbols/",
+			call: func(t *testing.T, api client.API) error {
+				releaseID, err := api.CreateRelease(model.ReleaseOptions{App: app, FilePath: writeFile(t, "app.apk")})
+				if err != nil {
+					t.Fatalf("CreateRelease() error = %v", err)
+				}
+
+				release, err := api.GetAppReleaseDetails(app, releaseID)
+				if err != nil {
+					t.Fatalf("GetAppReleaseDetails() error = %v", err)
+				}
+
+				return api.UploadSymbolToRelease(writeFile(t, "mapping.txt"), release, model.ReleaseOptions{App: app})
+			},
+			wantErr:      true,
+			wantRequests: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := fake.NewServer()
+			defer server.Close()
+
+			server.AddApp("owner", "app")
+			server.Fail(tt.failMethod, tt.failPath, http.StatusInternalServerError, tt.failTimes)
+
+			api, err := client.CreateAPIWithClientParams(fake.Token,
+				client.WithBaseURL(server.URL),
+				client.WithRetryPolicies(retries(tt.apiRetries), retries(tt.uploadRetries)),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = tt.call(t, api)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got := countRequests(server, tt.failMethod, tt.failPath); got != tt.wantRequests {
+				t.Errorf("%s %s requests = %d, want %d", tt.failMethod, tt.failPath, got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestUploadStatusMaxAttempts(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	app := server.AddApp("owner", "app")
+	app.UploadStatuses = []string{"uploadStarted"}
+
+	api, err := client.CreateAPIWithClientParams(fake.Token,
+		client.WithBaseURL(server.URL),
+		client.WithUploadStatusMaxAttempts(1),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = api.CreateRelease(model.ReleaseOptions{App: model.App{Owner: "owner", AppName: "app", AppType: model.AppTypeAndroid}, FilePath: writeFile(t, "app.apk")})
+	if err == nil || !strings.Contains(err.Error(), "uploadStarted") {
+		t.Fatalf("CreateRelease() error = %v, want the release not ready with its current status", err)
+	}
+
+	if got := countRequests(server, http.MethodGet, "^/v0.1/apps/owner/app/uploads/releases/"); got != 1 {
+		t.Errorf("upload status checks = %d, want 1", got)
+	}
+}
+
+func TestCreateAPIWithClientParams_invalidUploadStatusMaxAttempts(t *testing.T) {
+	if _, err := client.CreateAPIWithClientParams(fake.Token, client.WithBaseURL("https://api.appcenter.ms"), client.WithUploadStatusMaxAttempts(0)); err == nil {
+		t.Errorf("CreateAPIWithClientParams() error = nil, want an error for 0 upload status attempts")
+	}
+}
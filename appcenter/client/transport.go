@@ -0,0 +1,125 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+// TransportConfig configures how the client connects to App Center.
+type TransportConfig struct {
+	// ProxyURL is the proxy every request is sent through, if empty the HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables are used.
+	ProxyURL string
+	// CACertPath is a PEM bundle of CA certificates trusted in addition to the system ones, for example of a TLS-intercepting proxy.
+	CACertPath string
+	// ClientCertPath and ClientKeyPath are a PEM certificate and key presented to the server (mTLS), both or none of them has to be set.
+	ClientCertPath string
+	ClientKeyPath  string
+}
+
+// NewTransport returns a dedicated transport built from the config.
+func NewTransport(cfg TransportConfig) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if cfg.ProxyURL != "" {
+		proxyURL, err := parseProxyURL(cfg.ProxyURL)
+		if err != nil {
+			return nil, err
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if cfg.CACertPath != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			log.Warnf("Failed to load the system CA certificates, only the CA bundle is trusted: %s", err)
+			pool = x509.NewCertPool()
+		}
+
+		pem, err := os.ReadFile(cfg.CACertPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %s", err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA bundle (%s) contains no PEM certificate", cfg.CACertPath)
+		}
+
+		transport.TLSClientConfig.RootCAs = pool
+	}
+
+	if (cfg.ClientCertPath == "") != (cfg.ClientKeyPath == "") {
+		return nil, fmt.Errorf("client certificate and key have to be set together")
+	}
+
+	if cfg.ClientCertPath != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCertPath, cfg.ClientKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %s", err)
+		}
+
+		transport.TLSClientConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return transport, nil
+}
+
+func parseProxyURL(raw string) (*url.URL, error) {
+	proxyURL, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %s", Redact(err.Error()))
+	}
+
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("invalid proxy URL: scheme has to be http, https or socks5, got: %s", proxyURL.Scheme)
+	}
+
+	if proxyURL.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL: missing host")
+	}
+
+	return proxyURL, nil
+}
+
+// PrintTransport logs the proxy and TLS settings of the transport as debug logs, the proxy credentials are redacted.
+// The proxy taken from the environment is looked up for apiBaseURL, as NO_PROXY may exclude it.
+func PrintTransport(cfg TransportConfig, transport *http.Transport, apiBaseURL string) {
+	proxy := "from the environment"
+	if cfg.ProxyURL != "" {
+		if proxyURL, err := url.Parse(cfg.ProxyURL); err == nil {
+			proxy = Redact(proxyURL.Redacted())
+		}
+	} else if apiURL, err := url.Parse(apiBaseURL); err == nil {
+		if proxyURL, err := transport.Proxy(&http.Request{URL: apiURL}); err == nil && proxyURL != nil {
+			proxy = fmt.Sprintf("%s (from the environment)", Redact(proxyURL.Redacted()))
+		}
+	}
+	log.Debugf("Proxy: %s", proxy)
+
+	if cfg.CACertPath != "" {
+		log.Debugf("CA bundle: %s (trusted in addition to the system CAs)", cfg.CACertPath)
+	} else {
+		log.Debugf("CA bundle: system CAs")
+	}
+
+	for _, cert := range transport.TLSClientConfig.Certificates {
+		if len(cert.Certificate) == 0 {
+			continue
+		}
+
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			log.Debugf("Client certificate: %s", cfg.ClientCertPath)
+			continue
+		}
+
+		log.Debugf("Client certificate: %s (subject: %s, issuer: %s, expires: %s), key: %s", cfg.ClientCertPath, leaf.Subject, leaf.Issuer, leaf.NotAfter.Format("2006-01-02"), cfg.ClientKeyPath)
+	}
+}
@@ -0,0 +1,944 @@
+// Package fake implements an in-memory App Center API for hermetic tests of the step.
+//
+// The Server serves the App Center API, the upload domain (set_metadata, upload_chunk, finished)
+// and the symbol blob storage from the same address, so pointing the client's base URL at it
+// is enough to run the whole deploy flow offline. Failures, delays and upload status transitions
+// can be scripted per endpoint.
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/model"
+)
+
+const (
+	// Token is the only API token accepted by the server.
+	Token = "fake-api-token"
+
+	chunkSize = 4 * 1024 * 1024
+)
+
+// Rule scripts the response of the requests matching Method and Path.
+type Rule struct {
+	// Method matches the request method, empty matches every method.
+	Method string
+	// Path is a regular expression matched against the request path.
+	Path string
+	// Status is returned instead of serving the request, 0 serves the request.
+	Status int
+	// Body is the response body returned with Status.
+	Body string
+	// Header is added to the response returned with Status.
+	Header http.Header
+	// Delay is waited before responding.
+	Delay time.Duration
+	// Times limits how many requests the rule applies to, 0 means every request.
+	Times int
+
+	pattern *regexp.Regexp
+	hits    int
+}
+
+// Request is a request received by the server.
+type Request struct {
+	Method string
+	Path   string
+	Query  string
+	Body   []byte
+}
+
+// App is an App Center app served by the fake.
+type App struct {
+	Details model.AppDetails
+	Groups  []model.Group
+	Stores  []model.Store
+
+	// NewRelease is the template of the releases created by uploads.
+	NewRelease model.Release
+	// UploadStatuses are returned in order while the step polls the upload, the last one is repeated.
+	// Defaults to readyToBePublished.
+	UploadStatuses []string
+
+	Releases []*Release
+}
+
+// Release is a release of an app with the destinations it was distributed to.
+type Release struct {
+	model.Release
+
+	Groups        []string
+	Stores        []string
+	Testers       []string
+	SymbolUploads []string
+}
+
+type upload struct {
+	app        *App
+	id         string
+	assetID    string
+	fileName   string
+	fileSize   int
+	data       map[int][]byte
+	statusIdx  int
+	status     string
+	releaseID  int
+	finishedAt time.Time
+}
+
+type symbolUpload struct {
+	app          *App
+	id           string
+	build        string
+	shortVersion string
+	data         []byte
+}
+
+// Server is a fake App Center API.
+type Server struct {
+	*httptest.Server
+
+	mu            sync.Mutex
+	apps          map[string]*App
+	uploads       map[string]*upload
+	symbolUploads map[string]*symbolUpload
+	rules         []*Rule
+	requests      []Request
+	nextID        int
+}
+
+// NewServer starts a fake App Center API, Close it when done.
+func NewServer() *Server {
+	s := NewUnstartedServer()
+	s.Start()
+
+	return s
+}
+
+// NewUnstartedServer returns a fake App Center API which is not started yet,
+// so its Listener can be replaced, for example to serve on a fixed port.
+func NewUnstartedServer() *Server {
+	s := &Server{
+		apps:          map[string]*App{},
+		uploads:       map[string]*upload{},
+		symbolUploads: map[string]*symbolUpload{},
+		nextID:        1,
+	}
+	s.Server = httptest.NewUnstartedServer(http.HandlerFunc(s.serveHTTP))
+
+	return s
+}
+
+// AddApp registers an Android app and returns it for further setup.
+func (s *Server) AddApp(owner, name string) *App {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	app := &App{
+		Details: model.AppDetails{
+			ID:          fmt.Sprintf("%s-%s", owner, name),
+			Name:        name,
+			DisplayName: name,
+			OS:          "Android",
+			Platform:    "Java",
+			Owner:       model.AppOwner{ID: owner, Name: owner, DisplayName: owner, Type: model.OwnerTypeUser},
+		},
+		NewRelease: model.Release{
+			AppName:            name,
+			AppDisplayName:     name,
+			AppOs:              "Android",
+			Version:            "1",
+			ShortVersion:       "1.0",
+			AndroidMinAPILevel: "21",
+			BundleIdentifier:   "io.bitrise.fake",
+			Enabled:            true,
+		},
+	}
+	s.apps[owner+"/"+name] = app
+
+	return app
+}
+
+// AddGroup adds a distribution group to the app.
+func (a *App) AddGroup(name string, public bool) model.Group {
+	group := model.Group{
+		ID:          "group-" + name,
+		Name:        name,
+		DisplayName: name,
+		Origin:      "appcenter",
+		IsPublic:    public,
+	}
+	a.Groups = append(a.Groups, group)
+
+	return group
+}
+
+// AddStore adds a distribution store to the app.
+func (a *App) AddStore(name, storeType string) model.Store {
+	store := model.Store{ID: "store-" + name, Name: name, Type: storeType}
+	a.Stores = append(a.Stores, store)
+
+	return store
+}
+
+// AddRule scripts the responses of the matching requests, rules are evaluated in the order they were added.
+func (s *Server) AddRule(rule Rule) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rule.pattern = regexp.MustCompile(rule.Path)
+	s.rules = append(s.rules, &rule)
+}
+
+// Fail makes the next times matching requests fail with status (every matching request if times is 0).
+func (s *Server) Fail(method, path string, status, times int) {
+	s.AddRule(Rule{
+		Method: method,
+		Path:   path,
+		Status: status,
+		Body:   fmt.Sprintf(`{"code":"fake_error","message":"scripted %d response"}`, status),
+		Times:  times,
+	})
+}
+
+// Throttle responds 429 with the given Retry-After to the matching requests, at most times times (0 means every request).
+func (s *Server) Throttle(method, path string, retryAfter time.Duration, times int) {
+	s.AddRule(Rule{
+		Method: method,
+		Path:   path,
+		Status: http.StatusTooManyRequests,
+		Body:   `{"code":"TooManyRequests","message":"scripted rate limit"}`,
+		Header: http.Header{"Retry-After": []string{strconv.Itoa(int(retryAfter.Seconds()))}},
+		Times:  times,
+	})
+}
+
+// Delay delays every matching request.
+func (s *Server) Delay(method, path string, delay time.Duration) {
+	s.AddRule(Rule{Method: method, Path: path, Delay: delay})
+}
+
+// Requests returns the requests received so far.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Request(nil), s.requests...)
+}
+
+// Release returns a copy of a release of an app.
+func (s *Server) Release(owner, name string, id int) (Release, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	app, ok := s.apps[owner+"/"+name]
+	if !ok {
+		return Release{}, false
+	}
+
+	release := app.release(id)
+	if release == nil {
+		return Release{}, false
+	}
+
+	return *release, true
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{Method: r.Method, Path: r.URL.Path, Query: r.URL.RawQuery, Body: body})
+	rule := s.matchRule(r)
+	s.mu.Unlock()
+
+	if rule != nil {
+		if rule.Delay > 0 {
+			time.Sleep(rule.Delay)
+		}
+
+		if rule.Status != 0 {
+			for key, values := range rule.Header {
+				w.Header()[key] = values
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(rule.Status)
+			_, _ = w.Write([]byte(rule.Body))
+			return
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, route := range routes {
+		match := route.pattern.FindStringSubmatch(r.URL.Path)
+		if match == nil || route.method != r.Method {
+			continue
+		}
+
+		if route.api && r.Header.Get("x-api-token") != Token {
+			writeError(w, http.StatusUnauthorized, "unauthorized", "invalid API token")
+			return
+		}
+
+		route.handle(s, w, r, match[1:], body)
+		return
+	}
+
+	writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("no route for %s %s", r.Method, r.URL.Path))
+}
+
+// matchRule returns the first matching rule which is not used up, delay-only rules are combined with status rules.
+func (s *Server) matchRule(r *http.Request) *Rule {
+	var matched *Rule
+	for _, rule := range s.rules {
+		if rule.Method != "" && rule.Method != r.Method {
+			continue
+		}
+		if !rule.pattern.MatchString(r.URL.Path) {
+			continue
+		}
+		if rule.Times > 0 && rule.hits >= rule.Times {
+			continue
+		}
+
+		rule.hits++
+
+		if matched == nil {
+			copied := *rule
+			matched = &copied
+		} else {
+			if matched.Delay == 0 {
+				matched.Delay = rule.Delay
+			}
+			if matched.Status == 0 {
+				matched.Status = rule.Status
+				matched.Body = rule.Body
+				matched.Header = rule.Header
+			}
+		}
+	}
+
+	return matched
+}
+
+type route struct {
+	method  string
+	pattern *regexp.Regexp
+	api     bool
+	handle  func(s *Server, w http.ResponseWriter, r *http.Request, params []string, body []byte)
+}
+
+const appPath = `^/v0\.1/apps/([^/]+)/([^/]+)`
+
+var routes = []route{
+	{http.MethodGet, regexp.MustCompile(appPath + `$`), true, (*Server).getApp},
+	{http.MethodPost, regexp.MustCompile(appPath + `/uploads/releases$`), true, (*Server).createUpload},
+	{http.MethodPatch, regexp.MustCompile(appPath + `/uploads/releases/([^/]+)$`), true, (*Server).patchUpload},
+	{http.MethodGet, regexp.MustCompile(appPath + `/uploads/releases/([^/]+)$`), true, (*Server).getUpload},
+	{http.MethodGet, regexp.MustCompile(appPath + `/releases$`), true, (*Server).getReleases},
+	{http.MethodGet, regexp.MustCompile(appPath + `/releases/latest$`), true, (*Server).getLatestRelease},
+	{http.MethodGet, regexp.MustCompile(appPath + `/releases/(\d+)$`), true, (*Server).getRelease},
+	{http.MethodPut, regexp.MustCompile(appPath + `/releases/(\d+)$`), true, (*Server).putRelease},
+	{http.MethodPatch, regexp.MustCompile(appPath + `/releases/(\d+)$`), true, (*Server).patchRelease},
+	{http.MethodDelete, regexp.MustCompile(appPath + `/releases/(\d+)$`), true, (*Server).deleteRelease},
+	{http.MethodPost, regexp.MustCompile(appPath + `/releases/(\d+)/groups$`), true, (*Server).addReleaseToGroup},
+	{http.MethodPost, regexp.MustCompile(appPath + `/releases/(\d+)/stores$`), true, (*Server).addReleaseToStore},
+	{http.MethodPost, regexp.MustCompile(appPath + `/releases/(\d+)/testers$`), true, (*Server).addReleaseToTester},
+	{http.MethodGet, regexp.MustCompile(appPath + `/distribution_groups$`), true, (*Server).getGroups},
+	{http.MethodGet, regexp.MustCompile(appPath + `/distribution_groups/([^/]+)$`), true, (*Server).getGroup},
+	{http.MethodGet, regexp.MustCompile(appPath + `/distribution_groups/([^/]+)/releases$`), true, (*Server).getGroupReleases},
+	{http.MethodGet, regexp.MustCompile(appPath + `/distribution_groups/([^/]+)/releases/latest$`), true, (*Server).getGroupLatestRelease},
+	{http.MethodGet, regexp.MustCompile(appPath + `/distribution_stores/([^/]+)$`), true, (*Server).getStore},
+	{http.MethodPost, regexp.MustCompile(appPath + `/symbol_uploads$`), true, (*Server).createSymbolUpload},
+	{http.MethodPatch, regexp.MustCompile(appPath + `/symbol_uploads/([^/]+)$`), true, (*Server).patchSymbolUpload},
+	{http.MethodPost, regexp.MustCompile(`^/upload/set_metadata/([^/]+)$`), false, (*Server).setMetadata},
+	{http.MethodPost, regexp.MustCompile(`^/upload/upload_chunk/([^/]+)$`), false, (*Server).uploadChunk},
+	{http.MethodPost, regexp.MustCompile(`^/upload/finished/([^/]+)$`), false, (*Server).finishUpload},
+	{http.MethodPut, regexp.MustCompile(`^/blob/symbols/([^/]+)$`), false, (*Server).putSymbolBlob},
+}
+
+func (s *Server) app(w http.ResponseWriter, params []string) *App {
+	app, ok := s.apps[params[0]+"/"+params[1]]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("app %s/%s not found", params[0], params[1]))
+		return nil
+	}
+
+	return app
+}
+
+func (s *Server) appRelease(w http.ResponseWriter, params []string) (*App, *Release) {
+	app := s.app(w, params)
+	if app == nil {
+		return nil, nil
+	}
+
+	id, _ := strconv.Atoi(params[2])
+	release := app.release(id)
+	if release == nil {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("release %s not found", params[2]))
+		return nil, nil
+	}
+
+	return app, release
+}
+
+func (s *Server) getApp(w http.ResponseWriter, r *http.Request, params []string, body []byte) {
+	if app := s.app(w, params); app != nil {
+		writeJSON(w, http.StatusOK, app.Details)
+	}
+}
+
+func (s *Server) createUpload(w http.ResponseWriter, r *http.Request, params []string, body []byte) {
+	app := s.app(w, params)
+	if app == nil {
+		return
+	}
+
+	u := &upload{
+		app:     app,
+		id:      fmt.Sprintf("upload-%d", s.nextID),
+		assetID: fmt.Sprintf("asset-%d", s.nextID),
+		status:  "uploadStarted",
+		data:    map[int][]byte{},
+	}
+	s.nextID++
+	s.uploads[u.id] = u
+	s.uploads[u.assetID] = u
+
+	writeJSON(w, http.StatusCreated, map[string]string{
+		"id":                u.id,
+		"package_asset_id":  u.assetID,
+		"token":             Token,
+		"upload_domain":     s.URL,
+		"url_encoded_token": Token,
+	})
+}
+
+func (s *Server) setMetadata(w http.ResponseWriter, r *http.Request, params []string, body []byte) {
+	u := s.assetUpload(w, r, params[0])
+	if u == nil {
+		return
+	}
+
+	u.fileName = r.URL.Query().Get("file_name")
+	u.fileSize, _ = strconv.Atoi(r.URL.Query().Get("file_size"))
+
+	var chunks []int
+	for i := 0; i*chunkSize < u.fileSize; i++ {
+		chunks = append(chunks, i+1)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id":              u.assetID,
+		"chunk_size":      chunkSize,
+		"chunk_list":      chunks,
+		"blob_partitions": 1,
+	})
+}
+
+func (s *Server) uploadChunk(w http.ResponseWriter, r *http.Request, params []string, body []byte) {
+	u := s.assetUpload(w, r, params[0])
+	if u == nil {
+		return
+	}
+
+	blockNumber, err := strconv.Atoi(r.URL.Query().Get("block_number"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "invalid block_number")
+		return
+	}
+	u.data[blockNumber] = body
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"error": false})
+}
+
+func (s *Server) finishUpload(w http.ResponseWriter, r *http.Request, params []string, body []byte) {
+	u := s.assetUpload(w, r, params[0])
+	if u == nil {
+		return
+	}
+
+	size := 0
+	for _, chunk := range u.data {
+		size += len(chunk)
+	}
+	if size != u.fileSize {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"error": true, "error_code": "size_mismatch"})
+		return
+	}
+
+	u.finishedAt = time.Now()
+	writeJSON(w, http.StatusOK, map[string]interface{}{"error": false, "state": "Done"})
+}
+
+func (s *Server) assetUpload(w http.ResponseWriter, r *http.Request, assetID string) *upload {
+	if r.URL.Query().Get("token") != Token {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "invalid upload token")
+		return nil
+	}
+
+	u, ok := s.uploads[assetID]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("asset %s not found", assetID))
+		return nil
+	}
+
+	return u
+}
+
+func (s *Server) patchUpload(w http.ResponseWriter, r *http.Request, params []string, body []byte) {
+	u, ok := s.uploads[params[2]]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("upload %s not found", params[2]))
+		return
+	}
+
+	var patch struct {
+		UploadStatus string `json:"upload_status"`
+	}
+	if err := json.Unmarshal(body, &patch); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+	if patch.UploadStatus != "uploadFinished" || u.finishedAt.IsZero() {
+		writeError(w, http.StatusBadRequest, "bad_request", "upload is not finished")
+		return
+	}
+
+	u.status = patch.UploadStatus
+	writeJSON(w, http.StatusOK, map[string]string{"id": u.id, "upload_status": u.status})
+}
+
+func (s *Server) getUpload(w http.ResponseWriter, r *http.Request, params []string, body []byte) {
+	u, ok := s.uploads[params[2]]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("upload %s not found", params[2]))
+		return
+	}
+
+	if u.status != "uploadStarted" {
+		statuses := u.app.UploadStatuses
+		if len(statuses) == 0 {
+			statuses = []string{"readyToBePublished"}
+		}
+
+		idx := u.statusIdx
+		if idx >= len(statuses) {
+			idx = len(statuses) - 1
+		}
+		u.status = statuses[idx]
+		u.statusIdx++
+
+		if u.status == "readyToBePublished" && u.releaseID == 0 {
+			u.releaseID = s.createRelease(u)
+		}
+	}
+
+	response := map[string]interface{}{"id": u.id, "upload_status": u.status}
+	if u.releaseID != 0 {
+		response["release_distinct_id"] = u.releaseID
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) createRelease(u *upload) int {
+	release := &Release{Release: u.app.NewRelease}
+	release.ID = 1
+	for _, r := range u.app.Releases {
+		if r.ID >= release.ID {
+			release.ID = r.ID + 1
+		}
+	}
+
+	release.Size = u.fileSize
+	release.UploadedAt = u.finishedAt.UTC().Format(time.RFC3339)
+	release.Fingerprint = fmt.Sprintf("fingerprint-%d", release.ID)
+	release.DownloadURL = fmt.Sprintf("%s/download/%d/%s", s.URL, release.ID, u.fileName)
+	release.InstallURL = fmt.Sprintf("%s/install/%d", s.URL, release.ID)
+	release.Enabled = true
+
+	u.app.Releases = append(u.app.Releases, release)
+
+	return release.ID
+}
+
+func (a *App) release(id int) *Release {
+	for _, release := range a.Releases {
+		if release.ID == id {
+			return release
+		}
+	}
+
+	return nil
+}
+
+func (a *App) group(name string) (model.Group, bool) {
+	for _, group := range a.Groups {
+		if group.Name == name || group.ID == name {
+			return group, true
+		}
+	}
+
+	return model.Group{}, false
+}
+
+func (a *App) groupReleases(name string) []model.Release {
+	var releases []model.Release
+	for _, release := range a.Releases {
+		for _, group := range release.Groups {
+			if group == name {
+				releases = append(releases, release.Release)
+			}
+		}
+	}
+
+	sort.Slice(releases, func(i, j int) bool {
+		return releases[i].ID > releases[j].ID
+	})
+
+	return releases
+}
+
+func (s *Server) getReleases(w http.ResponseWriter, r *http.Request, params []string, body []byte) {
+	app := s.app(w, params)
+	if app == nil {
+		return
+	}
+
+	releases := []model.Release{}
+	for i := len(app.Releases) - 1; i >= 0; i-- {
+		releases = append(releases, app.Releases[i].Release)
+	}
+
+	writeJSON(w, http.StatusOK, releases)
+}
+
+func (s *Server) getLatestRelease(w http.ResponseWriter, r *http.Request, params []string, body []byte) {
+	app := s.app(w, params)
+	if app == nil {
+		return
+	}
+
+	if len(app.Releases) == 0 {
+		writeError(w, http.StatusNotFound, "not_found", "no releases")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, app.Releases[len(app.Releases)-1].Release)
+}
+
+func (s *Server) getRelease(w http.ResponseWriter, r *http.Request, params []string, body []byte) {
+	if _, release := s.appRelease(w, params); release != nil {
+		writeJSON(w, http.StatusOK, release.Release)
+	}
+}
+
+func (s *Server) putRelease(w http.ResponseWriter, r *http.Request, params []string, body []byte) {
+	_, release := s.appRelease(w, params)
+	if release == nil {
+		return
+	}
+
+	var update struct {
+		ReleaseNotes string `json:"release_notes"`
+	}
+	if err := json.Unmarshal(body, &update); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+
+	release.ReleaseNotes = update.ReleaseNotes
+	writeJSON(w, http.StatusOK, map[string]interface{}{"release_notes": release.ReleaseNotes})
+}
+
+func (s *Server) patchRelease(w http.ResponseWriter, r *http.Request, params []string, body []byte) {
+	_, release := s.appRelease(w, params)
+	if release == nil {
+		return
+	}
+
+	var update model.ReleaseUpdate
+	if err := json.Unmarshal(body, &update); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+
+	if update.Enabled != nil {
+		release.Enabled = *update.Enabled
+	}
+	if update.Build != nil {
+		release.Build = *update.Build
+	}
+
+	writeJSON(w, http.StatusOK, release.Release)
+}
+
+func (s *Server) deleteRelease(w http.ResponseWriter, r *http.Request, params []string, body []byte) {
+	app, release := s.appRelease(w, params)
+	if release == nil {
+		return
+	}
+
+	for i, r := range app.Releases {
+		if r == release {
+			app.Releases = append(app.Releases[:i], app.Releases[i+1:]...)
+			break
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Server) addReleaseToGroup(w http.ResponseWriter, r *http.Request, params []string, body []byte) {
+	app, release := s.appRelease(w, params)
+	if release == nil {
+		return
+	}
+
+	var request struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(body, &request); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+
+	group, ok := app.group(request.ID)
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("group %s not found", request.ID))
+		return
+	}
+
+	release.Groups = append(release.Groups, group.Name)
+	release.DistributionGroups = append(release.DistributionGroups, struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}{ID: group.ID, Name: group.Name})
+	release.addDestination(group.ID, group.Name, "group")
+
+	writeJSON(w, http.StatusCreated, map[string]string{"id": group.ID})
+}
+
+func (s *Server) addReleaseToStore(w http.ResponseWriter, r *http.Request, params []string, body []byte) {
+	app, release := s.appRelease(w, params)
+	if release == nil {
+		return
+	}
+
+	var request struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(body, &request); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+
+	for _, store := range app.Stores {
+		if store.ID == request.ID {
+			release.Stores = append(release.Stores, store.Name)
+			release.addDestination(store.ID, store.Name, "store")
+
+			writeJSON(w, http.StatusCreated, map[string]string{"id": store.ID})
+			return
+		}
+	}
+
+	writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("store %s not found", request.ID))
+}
+
+func (s *Server) addReleaseToTester(w http.ResponseWriter, r *http.Request, params []string, body []byte) {
+	_, release := s.appRelease(w, params)
+	if release == nil {
+		return
+	}
+
+	var request struct {
+		Email string `json:"email"`
+	}
+	if err := json.Unmarshal(body, &request); err != nil || !strings.Contains(request.Email, "@") {
+		writeError(w, http.StatusBadRequest, "bad_request", "invalid email")
+		return
+	}
+
+	release.Testers = append(release.Testers, request.Email)
+	release.addDestination(request.Email, request.Email, "tester")
+
+	writeJSON(w, http.StatusCreated, map[string]string{"email": request.Email})
+}
+
+func (r *Release) addDestination(id, name, destinationType string) {
+	r.Destinations = append(r.Destinations, struct {
+		ID               string `json:"id"`
+		Name             string `json:"name"`
+		IsLatest         bool   `json:"is_latest"`
+		Type             string `json:"type"`
+		PublishingStatus string `json:"publishing_status"`
+		DestinationType  string `json:"destination_type"`
+		DisplayName      string `json:"display_name"`
+	}{ID: id, Name: name, IsLatest: true, DestinationType: destinationType, DisplayName: name})
+}
+
+func (s *Server) getGroups(w http.ResponseWriter, r *http.Request, params []string, body []byte) {
+	if app := s.app(w, params); app != nil {
+		writeJSON(w, http.StatusOK, append([]model.Group{}, app.Groups...))
+	}
+}
+
+func (s *Server) getGroup(w http.ResponseWriter, r *http.Request, params []string, body []byte) {
+	app := s.app(w, params)
+	if app == nil {
+		return
+	}
+
+	group, ok := app.group(params[2])
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("group %s not found", params[2]))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, group)
+}
+
+func (s *Server) getGroupReleases(w http.ResponseWriter, r *http.Request, params []string, body []byte) {
+	if app := s.app(w, params); app != nil {
+		writeJSON(w, http.StatusOK, append([]model.Release{}, app.groupReleases(params[2])...))
+	}
+}
+
+func (s *Server) getGroupLatestRelease(w http.ResponseWriter, r *http.Request, params []string, body []byte) {
+	app := s.app(w, params)
+	if app == nil {
+		return
+	}
+
+	// Disabled releases are not available to the testers of the group.
+	for _, release := range app.groupReleases(params[2]) {
+		if release.Enabled {
+			writeJSON(w, http.StatusOK, release)
+			return
+		}
+	}
+
+	writeError(w, http.StatusNotFound, "not_found", "no releases in group")
+}
+
+func (s *Server) getStore(w http.ResponseWriter, r *http.Request, params []string, body []byte) {
+	app := s.app(w, params)
+	if app == nil {
+		return
+	}
+
+	for _, store := range app.Stores {
+		if store.Name == params[2] {
+			writeJSON(w, http.StatusOK, store)
+			return
+		}
+	}
+
+	writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("store %s not found", params[2]))
+}
+
+func (s *Server) createSymbolUpload(w http.ResponseWriter, r *http.Request, params []string, body []byte) {
+	app := s.app(w, params)
+	if app == nil {
+		return
+	}
+
+	var request struct {
+		SymbolType string `json:"symbol_type"`
+		Build      string `json:"build"`
+		Version    string `json:"version"`
+	}
+	if err := json.Unmarshal(body, &request); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+
+	if request.SymbolType == "" {
+		writeError(w, http.StatusBadRequest, "bad_request", "missing symbol_type")
+		return
+	}
+
+	upload := &symbolUpload{
+		app:          app,
+		id:           fmt.Sprintf("symbol-%d", s.nextID),
+		build:        request.Build,
+		shortVersion: request.Version,
+	}
+	s.nextID++
+	s.symbolUploads[upload.id] = upload
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"symbol_upload_id": upload.id,
+		"upload_url":       fmt.Sprintf("%s/blob/symbols/%s?sv=2019-02-02&sig=fake-signature", s.URL, upload.id),
+		"expiration_date":  time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+	})
+}
+
+func (s *Server) putSymbolBlob(w http.ResponseWriter, r *http.Request, params []string, body []byte) {
+	upload, ok := s.symbolUploads[params[0]]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("symbol upload %s not found", params[0]))
+		return
+	}
+
+	upload.data = body
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *Server) patchSymbolUpload(w http.ResponseWriter, r *http.Request, params []string, body []byte) {
+	upload, ok := s.symbolUploads[params[2]]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("symbol upload %s not found", params[2]))
+		return
+	}
+
+	if upload.data == nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "symbol file was not uploaded")
+		return
+	}
+
+	for i := len(upload.app.Releases) - 1; i >= 0; i-- {
+		release := upload.app.Releases[i]
+		if release.Version == upload.build && release.ShortVersion == upload.shortVersion {
+			release.SymbolUploads = append(release.SymbolUploads, upload.id)
+			break
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"symbol_upload_id": upload.id, "status": "committed"})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(b)
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, model.Error{Code: code, Message: message})
+}
@@ -0,0 +1,37 @@
+package model
+
+// App ...
+type App struct {
+	Owner   string
+	AppName string
+	AppType AppType
+}
+
+// OwnerType ...
+type OwnerType string
+
+// OwnerTypes ...
+const (
+	OwnerTypeUser OwnerType = "user"
+	OwnerTypeOrg  OwnerType = "org"
+)
+
+// AppOwner ...
+type AppOwner struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	DisplayName string    `json:"display_name"`
+	Type        OwnerType `json:"type"`
+}
+
+// AppDetails ...
+type AppDetails struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	DisplayName string   `json:"display_name"`
+	OS          string   `json:"os"`
+	Platform    string   `json:"platform"`
+	IconURL     string   `json:"icon_url"`
+	Owner       AppOwner `json:"owner"`
+	Error       Error    `json:"error"`
+}
@@ -45,15 +45,24 @@ type Release struct {
 		DestinationType  string `json:"destination_type"`
 		DisplayName      string `json:"display_name"`
 	} `json:"destinations"`
-	IsUdidProvisioned bool `json:"is_udid_provisioned"`
-	CanResign         bool `json:"can_resign"`
-	Build             struct {
-		BranchName    string `json:"branch_name"`
-		CommitHash    string `json:"commit_hash"`
-		CommitMessage string `json:"commit_message"`
-	} `json:"build"`
-	Enabled         bool   `json:"enabled"`
-	Status          string `json:"status"`
-	IsExternalBuild bool   `json:"is_external_build"`
-	Error           Error  `json:"error"`
+	IsUdidProvisioned bool         `json:"is_udid_provisioned"`
+	CanResign         bool         `json:"can_resign"`
+	Build             ReleaseBuild `json:"build"`
+	Enabled           bool         `json:"enabled"`
+	Status            string       `json:"status"`
+	IsExternalBuild   bool         `json:"is_external_build"`
+	Error             Error        `json:"error"`
+}
+
+// ReleaseBuild ...
+type ReleaseBuild struct {
+	BranchName    string `json:"branch_name,omitempty"`
+	CommitHash    string `json:"commit_hash,omitempty"`
+	CommitMessage string `json:"commit_message,omitempty"`
+}
+
+// ReleaseUpdate - fields left nil are not changed
+type ReleaseUpdate struct {
+	Enabled *bool         `json:"enabled,omitempty"`
+	Build   *ReleaseBuild `json:"build,omitempty"`
 }
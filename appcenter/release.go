@@ -3,8 +3,8 @@ package appcenter
 import (
 	"strings"
 
-	"github.com/bitrise-io/appcenter/client"
-	"github.com/bitrise-io/appcenter/model"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/client"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/model"
 )
 
 // ReleaseAPI ...
@@ -60,11 +60,31 @@ func (r ReleaseAPI) AddTester(email string) error {
 	return r.API.AddTesterToRelease(email, r.Release.ID, r.ReleaseOptions)
 }
 
+// Details ...
+func (r ReleaseAPI) Details() (model.Release, error) {
+	return r.API.GetAppReleaseDetails(r.ReleaseOptions.App, r.Release.ID)
+}
+
 // SetReleaseNote ...
 func (r ReleaseAPI) SetReleaseNote(releaseNote string) error {
 	return r.API.SetReleaseNoteOnRelease(releaseNote, r.Release.ID, r.ReleaseOptions)
 }
 
+// SetBuild ...
+func (r ReleaseAPI) SetBuild(build model.ReleaseBuild) error {
+	return r.API.UpdateRelease(model.ReleaseUpdate{Build: &build}, r.Release.ID, r.ReleaseOptions)
+}
+
+// SetEnabled ...
+func (r ReleaseAPI) SetEnabled(enabled bool) error {
+	return r.API.UpdateRelease(model.ReleaseUpdate{Enabled: &enabled}, r.Release.ID, r.ReleaseOptions)
+}
+
+// Delete ...
+func (r ReleaseAPI) Delete() error {
+	return r.API.DeleteRelease(r.Release.ID, r.ReleaseOptions)
+}
+
 // UploadSymbol - build and version is required for Android and optional for iOS
 func (r ReleaseAPI) UploadSymbol(filePath string) error {
 	return r.API.UploadSymbolToRelease(filePath, r.Release, r.ReleaseOptions)
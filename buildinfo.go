@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-utils/retry"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/model"
+)
+
+const (
+	buildCheckRetries  = 10
+	buildCheckWaitTime = 3 * time.Second
+)
+
+func newReleaseBuild(cfg config) model.ReleaseBuild {
+	return model.ReleaseBuild{
+		BranchName:    strings.TrimSpace(cfg.BuildBranch),
+		CommitHash:    strings.TrimSpace(cfg.BuildCommitHash),
+		CommitMessage: strings.TrimSpace(cfg.BuildCommitMessage),
+	}
+}
+
+func isEmptyBuild(build model.ReleaseBuild) bool {
+	return build == model.ReleaseBuild{}
+}
+
+// setReleaseBuild attaches the build metadata to the release and re-fetches the release until App Center returns it.
+func setReleaseBuild(releaseAPI appcenter.ReleaseAPI, build model.ReleaseBuild) error {
+	if err := releaseAPI.SetBuild(build); err != nil {
+		return err
+	}
+
+	return retry.Times(buildCheckRetries).Wait(buildCheckWaitTime).Try(func(attempt uint) error {
+		release, err := releaseAPI.Details()
+		if err != nil {
+			return fmt.Errorf("failed to fetch release details: %s", err)
+		}
+
+		if release.Build != build {
+			return fmt.Errorf("build metadata of release %d does not match, expected: %+v, got: %+v", release.ID, build, release.Build)
+		}
+
+		return nil
+	})
+}
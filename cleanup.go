@@ -0,0 +1,242 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/client"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/model"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/deployer"
+)
+
+const (
+	modeCleanup = "cleanup"
+
+	cleanupActionDelete  = "delete"
+	cleanupActionDisable = "disable"
+
+	cleanedUpReleaseIDsEnvKey = "APPCENTER_CLEANUP_RELEASE_IDS"
+)
+
+// cleanupPolicy selects the releases removed in cleanup mode.
+type cleanupPolicy struct {
+	olderThan     time.Duration
+	keepLatest    int
+	branchPattern *regexp.Regexp
+	// group is the distribution group the releases are filtered by, it is empty if no group is set.
+	group model.Group
+}
+
+// newCleanupPolicy returns the policy of the cleanup inputs, the group is looked up among the app's groups.
+func newCleanupPolicy(cfg config, groups []model.Group) (cleanupPolicy, error) {
+	if cfg.CleanupOlderThanDays <= 0 && cfg.CleanupKeepLatest <= 0 {
+		return cleanupPolicy{}, fmt.Errorf("either cleanup_older_than_days or cleanup_keep_latest is required in cleanup mode")
+	}
+
+	policy := cleanupPolicy{
+		olderThan:  time.Duration(cfg.CleanupOlderThanDays) * 24 * time.Hour,
+		keepLatest: cfg.CleanupKeepLatest,
+	}
+
+	if cfg.CleanupBranchPattern != "" {
+		pattern, err := regexp.Compile(cfg.CleanupBranchPattern)
+		if err != nil {
+			return cleanupPolicy{}, fmt.Errorf("invalid branch pattern: %s", err)
+		}
+
+		policy.branchPattern = pattern
+	}
+
+	if groupName := strings.TrimSpace(cfg.CleanupGroup); groupName != "" {
+		group, err := deployer.LookupGroup(groups, groupName)
+		if err != nil {
+			return cleanupPolicy{}, fmt.Errorf("cleanup_group (%s): %w", groupName, err)
+		}
+
+		policy.group = group
+	}
+
+	return policy, nil
+}
+
+// matches reports whether the release is in the scope of the cleanup (branch and group filters).
+func (p cleanupPolicy) matches(release model.Release) bool {
+	if p.branchPattern != nil && !p.branchPattern.MatchString(release.Build.BranchName) {
+		return false
+	}
+
+	if p.group.Name != "" {
+		inGroup := false
+		for _, group := range release.DistributionGroups {
+			if p.isGroup(group.ID, group.Name) {
+				inGroup = true
+				break
+			}
+		}
+		for _, dest := range release.Destinations {
+			if dest.DestinationType == "group" && p.isGroup(dest.ID, dest.Name) {
+				inGroup = true
+				break
+			}
+		}
+
+		if !inGroup {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isGroup reports whether the group of a release, given by its ID and name, is the group of the policy.
+func (p cleanupPolicy) isGroup(id, name string) bool {
+	return name == p.group.Name || (id != "" && id == p.group.ID)
+}
+
+// isLatestInAnyDestination reports whether the release is the latest one of a group or store, these releases are never removed.
+func isLatestInAnyDestination(release model.Release) bool {
+	for _, dest := range release.Destinations {
+		if dest.IsLatest {
+			return true
+		}
+	}
+
+	return false
+}
+
+// selectReleases returns the releases to remove, newest first.
+// A matching release is removed if it is beyond the newest keepLatest matching releases or older than olderThan,
+// releases which are protected or the latest in a destination are never removed.
+func (p cleanupPolicy) selectReleases(releases []model.Release, protectedIDs []int, now time.Time) []model.Release {
+	var matching []model.Release
+	for _, release := range releases {
+		if p.matches(release) {
+			matching = append(matching, release)
+		}
+	}
+
+	sort.Slice(matching, func(i, j int) bool {
+		return matching[i].ID > matching[j].ID
+	})
+
+	var selected []model.Release
+	for idx, release := range matching {
+		if isLatestInAnyDestination(release) || containsInt(protectedIDs, release.ID) {
+			continue
+		}
+
+		if (p.keepLatest > 0 && idx >= p.keepLatest) || p.isOld(release, now) {
+			selected = append(selected, release)
+		}
+	}
+
+	return selected
+}
+
+// isOld reports whether the release was uploaded more than olderThan before now, it is false if no age limit is set.
+func (p cleanupPolicy) isOld(release model.Release, now time.Time) bool {
+	if p.olderThan <= 0 {
+		return false
+	}
+
+	uploadedAt, err := time.Parse(time.RFC3339, release.UploadedAt)
+	if err != nil {
+		log.Warnf("Skipping the age of release %d, invalid upload date: %s", release.ID, release.UploadedAt)
+		return false
+	}
+
+	return now.Sub(uploadedAt) >= p.olderThan
+}
+
+// cleanup deletes or disables the old releases of the app selected by the cleanup inputs.
+func cleanup(cfg config, api client.API, appAPI appcenter.AppAPI, releaseOptions model.ReleaseOptions, groups []model.Group) {
+	policy, err := newCleanupPolicy(cfg, groups)
+	if err != nil {
+		failf("Issue with input: %s", err)
+	}
+
+	log.Infof("Fetching releases")
+
+	releases, err := appAPI.Releases()
+	if err != nil {
+		failf("Failed to fetch releases, error: %s", err)
+	}
+
+	latest, err := appAPI.LatestRelease()
+	if err != nil {
+		failf("Failed to fetch the latest release, error: %s", err)
+	}
+
+	log.Printf("- %d release(s), latest: %d", len(releases), latest.ID)
+	log.Donef("- Done")
+	fmt.Println()
+
+	var selected []model.Release
+	for _, release := range policy.selectReleases(releases, []int{latest.ID}, time.Now()) {
+		if cfg.CleanupAction == cleanupActionDisable && !release.Enabled {
+			continue
+		}
+
+		selected = append(selected, release)
+	}
+
+	log.Infof("Release(s) to %s: %d", cfg.CleanupAction, len(selected))
+	for _, release := range selected {
+		log.Printf("- %d (%s (%s)), uploaded at: %s, branch: %s", release.ID, release.ShortVersion, release.Version, release.UploadedAt, release.Build.BranchName)
+	}
+	fmt.Println()
+
+	var ids []string
+	for _, release := range selected {
+		ids = append(ids, strconv.Itoa(release.ID))
+	}
+
+	if cfg.DryRun {
+		log.Infof("Exporting outputs")
+		exportOutputs(map[string]string{
+			statusEnvKey:              dryRunStatus,
+			cleanedUpReleaseIDsEnvKey: strings.Join(ids, ","),
+		})
+		log.Donef("- Done")
+		log.Warnf("Dry run: no release was removed")
+		return
+	}
+
+	if len(selected) > 0 {
+		log.Infof("Cleaning up release(s)")
+
+		for _, release := range selected {
+			releaseAPI := appcenter.CreateReleaseAPI(api, release, releaseOptions)
+
+			var err error
+			if cfg.CleanupAction == cleanupActionDelete {
+				err = releaseAPI.Delete()
+			} else {
+				err = releaseAPI.SetEnabled(false)
+			}
+			if err != nil {
+				failf("Failed to %s release (%d), error: %s", cfg.CleanupAction, release.ID, err)
+			}
+
+			log.Printf("- %d", release.ID)
+		}
+
+		log.Donef("- Done")
+		fmt.Println()
+	}
+
+	log.Infof("Exporting outputs")
+
+	exportOutputs(map[string]string{
+		statusEnvKey:              "success",
+		cleanedUpReleaseIDsEnvKey: strings.Join(ids, ","),
+	})
+
+	log.Donef("- Done")
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/model"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/deployer"
+)
+
+// cleanupNow is the time the cleanup tests run at.
+var cleanupNow = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// cleanupRelease returns a release uploaded the given number of days before now, its destinations are given as JSON.
+func cleanupRelease(t *testing.T, id, daysOld int, branch, destinations string) model.Release {
+	release := model.Release{
+		ID:         id,
+		UploadedAt: cleanupNow.Add(-time.Duration(daysOld) * 24 * time.Hour).Format(time.RFC3339),
+		Build:      model.ReleaseBuild{BranchName: branch},
+	}
+	if destinations != "" {
+		if err := json.Unmarshal([]byte(destinations), &release.Destinations); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return release
+}
+
+func Test_cleanupPolicy_selectReleases(t *testing.T) {
+	beta := `[{"id":"group-beta","name":"beta","destination_type":"group"}]`
+	latestInBeta := `[{"id":"group-beta","name":"beta","destination_type":"group","is_latest":true}]`
+	alpha := `[{"id":"group-alpha","name":"alpha","destination_type":"group"}]`
+
+	releases := []model.Release{
+		cleanupRelease(t, 1, 40, "pr/1", beta),
+		cleanupRelease(t, 2, 35, "main", alpha),
+		cleanupRelease(t, 3, 20, "pr/2", beta),
+		cleanupRelease(t, 4, 10, "pr/3", latestInBeta),
+		cleanupRelease(t, 5, 5, "main", alpha),
+		cleanupRelease(t, 6, 1, "pr/4", ""),
+	}
+
+	tests := []struct {
+		name         string
+		policy       cleanupPolicy
+		protectedIDs []int
+		want         []int
+	}{
+		{
+			name:   "keep_latest removes the releases beyond the newest ones",
+			policy: cleanupPolicy{keepLatest: 3},
+			want:   []int{3, 2, 1},
+		},
+		{
+			name:   "older_than_days removes the old releases",
+			policy: cleanupPolicy{olderThan: 30 * 24 * time.Hour},
+			want:   []int{2, 1},
+		},
+		{
+			name:   "older_than_days or keep_latest",
+			policy: cleanupPolicy{olderThan: 30 * 24 * time.Hour, keepLatest: 5},
+			want:   []int{2, 1},
+		},
+		{
+			name:   "keep_latest or older_than_days",
+			policy: cleanupPolicy{olderThan: 7 * 24 * time.Hour, keepLatest: 5},
+			want:   []int{3, 2, 1},
+		},
+		{
+			name:   "branch pattern",
+			policy: cleanupPolicy{keepLatest: 1, branchPattern: regexp.MustCompile(`^pr/`)},
+			want:   []int{3, 1},
+		},
+		{
+			name:   "group filter by name",
+			policy: cleanupPolicy{keepLatest: 1, group: model.Group{ID: "group-alpha", Name: "alpha"}},
+			want:   []int{2},
+		},
+		{
+			name:   "group filter by ID",
+			policy: cleanupPolicy{olderThan: 24 * time.Hour, group: model.Group{ID: "group-beta", Name: "renamed"}},
+			want:   []int{3, 1},
+		},
+		{
+			name:   "release latest in a destination is protected",
+			policy: cleanupPolicy{olderThan: 7 * 24 * time.Hour, group: model.Group{ID: "group-beta", Name: "beta"}},
+			want:   []int{3, 1},
+		},
+		{
+			name:         "protected release is kept",
+			policy:       cleanupPolicy{keepLatest: 3},
+			protectedIDs: []int{2},
+			want:         []int{3, 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int
+			for _, release := range tt.policy.selectReleases(releases, tt.protectedIDs, cleanupNow) {
+				got = append(got, release.ID)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selectReleases() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_newCleanupPolicy_group(t *testing.T) {
+	groups := []model.Group{
+		{ID: "group-beta", Name: "beta-testers", DisplayName: "Beta Testers"},
+		{ID: "group-alpha", Name: "alpha", DisplayName: "Alpha"},
+	}
+
+	tests := []struct {
+		name         string
+		cleanupGroup string
+		want         model.Group
+		wantNotFound bool
+	}{
+		{name: "no group"},
+		{name: "group by name", cleanupGroup: "beta-testers", want: groups[0]},
+		{name: "group by display name", cleanupGroup: " beta testers ", want: groups[0]},
+		{name: "unknown group", cleanupGroup: "Gamma", wantNotFound: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := newCleanupPolicy(config{CleanupKeepLatest: 1, CleanupGroup: tt.cleanupGroup}, groups)
+
+			var notFound *deployer.GroupNotFoundError
+			if errors.As(err, &notFound) != tt.wantNotFound {
+				t.Fatalf("newCleanupPolicy() error = %v, wantNotFound %v", err, tt.wantNotFound)
+			}
+			if tt.wantNotFound {
+				if want := []string{"Beta Testers", "Alpha"}; !reflect.DeepEqual(notFound.Available, want) {
+					t.Errorf("newCleanupPolicy() available groups = %v, want %v", notFound.Available, want)
+				}
+				return
+			}
+
+			if policy.group != tt.want {
+				t.Errorf("newCleanupPolicy() group = %+v, want %+v", policy.group, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/model"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/deployer"
+)
+
+const releaseComparisonEnvKey = "APPCENTER_DEPLOY_RELEASE_COMPARISON"
+
+// releaseBudget is the allowed change between the previous and the new release.
+type releaseBudget struct {
+	maxSizeIncreasePercent float64
+	maxSizeIncreaseBytes   int
+	allowMinAPIChange      bool
+}
+
+func newReleaseBudget(cfg config) releaseBudget {
+	return releaseBudget{
+		maxSizeIncreasePercent: cfg.MaxSizeIncreasePercent,
+		maxSizeIncreaseBytes:   cfg.MaxSizeIncreaseBytes,
+		allowMinAPIChange:      cfg.AllowMinAPIChange,
+	}
+}
+
+// releaseComparison is the difference between the previous and the new release.
+type releaseComparison struct {
+	PreviousReleaseID    int      `json:"previous_release_id"`
+	PreviousVersion      string   `json:"previous_version"`
+	PreviousShortVersion string   `json:"previous_short_version"`
+	PreviousSize         int      `json:"previous_size"`
+	PreviousMinAPILevel  string   `json:"previous_android_min_api_level"`
+	Version              string   `json:"version"`
+	ShortVersion         string   `json:"short_version"`
+	Size                 int      `json:"size"`
+	MinAPILevel          string   `json:"android_min_api_level"`
+	SizeDelta            int      `json:"size_delta"`
+	SizeDeltaPercent     float64  `json:"size_delta_percent"`
+	MinAPILevelChanged   bool     `json:"android_min_api_level_changed"`
+	Violations           []string `json:"violations"`
+}
+
+// compareReleases compares the new release with the previous one and checks the budget.
+// Without a previous release (ID 0) only the new release's details are set and there is nothing to violate.
+func compareReleases(previous, current model.Release, budget releaseBudget) releaseComparison {
+	comparison := releaseComparison{
+		Version:      current.Version,
+		ShortVersion: current.ShortVersion,
+		Size:         current.Size,
+		MinAPILevel:  current.AndroidMinAPILevel,
+		Violations:   []string{},
+	}
+
+	if previous.ID == 0 {
+		return comparison
+	}
+
+	comparison.PreviousReleaseID = previous.ID
+	comparison.PreviousVersion = previous.Version
+	comparison.PreviousShortVersion = previous.ShortVersion
+	comparison.PreviousSize = previous.Size
+	comparison.PreviousMinAPILevel = previous.AndroidMinAPILevel
+	comparison.SizeDelta = current.Size - previous.Size
+	comparison.MinAPILevelChanged = previous.AndroidMinAPILevel != "" && previous.AndroidMinAPILevel != current.AndroidMinAPILevel
+
+	if previous.Size > 0 {
+		comparison.SizeDeltaPercent = float64(comparison.SizeDelta) / float64(previous.Size) * 100
+	}
+
+	if budget.maxSizeIncreasePercent > 0 && comparison.SizeDeltaPercent > budget.maxSizeIncreasePercent {
+		comparison.Violations = append(comparison.Violations, fmt.Sprintf("size increased by %.2f%%, the limit is %.2f%%", comparison.SizeDeltaPercent, budget.maxSizeIncreasePercent))
+	}
+
+	if budget.maxSizeIncreaseBytes > 0 && comparison.SizeDelta > budget.maxSizeIncreaseBytes {
+		comparison.Violations = append(comparison.Violations, fmt.Sprintf("size increased by %d bytes, the limit is %d bytes", comparison.SizeDelta, budget.maxSizeIncreaseBytes))
+	}
+
+	if comparison.MinAPILevelChanged && !budget.allowMinAPIChange {
+		comparison.Violations = append(comparison.Violations, fmt.Sprintf("min API level changed from %s to %s, set allow_min_api_change to allow it", previous.AndroidMinAPILevel, current.AndroidMinAPILevel))
+	}
+
+	return comparison
+}
+
+func (c releaseComparison) print() {
+	log.Printf("- Previous release: %d", c.PreviousReleaseID)
+	log.Printf("- Version: %s (%s) -> %s (%s)", c.PreviousShortVersion, c.PreviousVersion, c.ShortVersion, c.Version)
+	log.Printf("- Size: %d -> %d bytes (%+d bytes, %+.2f%%)", c.PreviousSize, c.Size, c.SizeDelta, c.SizeDeltaPercent)
+	log.Printf("- Min API level: %s -> %s", c.PreviousMinAPILevel, c.MinAPILevel)
+}
+
+func (c releaseComparison) json() (string, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// comparedGroup returns the group whose latest release the new release is compared with:
+// compare_group if set, otherwise the first distribution group, or empty to compare with the app's latest release.
+// compare_group has to be one of the app's groups, a typo would silently compare with nothing.
+func comparedGroup(cfg config, groups []model.Group, plan deployer.Plan) (string, error) {
+	if cfg.CompareGroup != "" {
+		group, err := deployer.LookupGroup(groups, cfg.CompareGroup)
+		if err != nil {
+			return "", fmt.Errorf("compare_group (%s): %w", cfg.CompareGroup, err)
+		}
+
+		return group.Name, nil
+	}
+
+	for _, planned := range plan.Groups {
+		if planned.Found {
+			return planned.Group.Name, nil
+		}
+	}
+
+	return "", nil
+}
+
+// fetchPreviousRelease returns the latest release of the group (or the app, if group is empty),
+// or an empty release if there is none. It has to be called before the new release is uploaded.
+func fetchPreviousRelease(appAPI appcenter.AppAPI, group string) (model.Release, error) {
+	var previous model.Release
+	var err error
+	if group != "" {
+		previous, err = appAPI.LatestReleaseInGroup(group)
+	} else {
+		previous, err = appAPI.LatestRelease()
+	}
+	if err != nil || previous.ID == 0 {
+		return previous, err
+	}
+
+	// The latest release endpoints do not return every detail of the release.
+	return appAPI.ReleaseDetails(previous.ID)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/model"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/deployer"
+)
+
+func Test_comparedGroup(t *testing.T) {
+	groups := []model.Group{
+		{Name: "collaborators", DisplayName: "Collaborators"},
+		{Name: "beta-testers", DisplayName: "Beta Testers"},
+	}
+	plan := deployer.Plan{Groups: []deployer.PlannedGroup{
+		{Name: "Missing"},
+		{Name: "Beta Testers", Group: groups[1], Found: true},
+	}}
+
+	tests := []struct {
+		name         string
+		compareGroup string
+		plan         deployer.Plan
+		want         string
+		wantNotFound bool
+	}{
+		{
+			name:         "compare_group",
+			compareGroup: "collaborators",
+			plan:         plan,
+			want:         "collaborators",
+		},
+		{
+			name:         "unknown compare_group",
+			compareGroup: "Colaborators",
+			plan:         plan,
+			wantNotFound: true,
+		},
+		{
+			name: "first found distribution group",
+			plan: plan,
+			want: "beta-testers",
+		},
+		{
+			name: "no distribution group",
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := comparedGroup(config{CompareGroup: tt.compareGroup}, groups, tt.plan)
+
+			var notFound *deployer.GroupNotFoundError
+			if errors.As(err, &notFound) != tt.wantNotFound {
+				t.Fatalf("comparedGroup() error = %v, wantNotFound %v", err, tt.wantNotFound)
+			}
+			if got != tt.want {
+				t.Errorf("comparedGroup() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_compareReleases(t *testing.T) {
+	current := model.Release{ID: 2, Version: "2", ShortVersion: "1.1", Size: 1200, AndroidMinAPILevel: "23"}
+
+	t.Run("without previous release", func(t *testing.T) {
+		got := compareReleases(model.Release{}, current, releaseBudget{maxSizeIncreaseBytes: 1, allowMinAPIChange: false})
+
+		if got.PreviousReleaseID != 0 || got.SizeDelta != 0 || got.MinAPILevelChanged {
+			t.Errorf("compareReleases() = %+v, want only the new release's details", got)
+		}
+		if got.Size != current.Size || got.Version != current.Version {
+			t.Errorf("compareReleases() = %+v, want the new release's details", got)
+		}
+		if got.Violations == nil || len(got.Violations) != 0 {
+			t.Errorf("compareReleases() violations = %#v, want empty", got.Violations)
+		}
+	})
+
+	t.Run("over budget", func(t *testing.T) {
+		previous := model.Release{ID: 1, Version: "1", ShortVersion: "1.0", Size: 1000, AndroidMinAPILevel: "21"}
+
+		got := compareReleases(previous, current, releaseBudget{maxSizeIncreasePercent: 10, maxSizeIncreaseBytes: 100})
+
+		if got.SizeDelta != 200 || got.SizeDeltaPercent != 20 || !got.MinAPILevelChanged {
+			t.Errorf("compareReleases() = %+v", got)
+		}
+		if len(got.Violations) != 3 {
+			t.Errorf("compareReleases() violations = %v, want 3", got.Violations)
+		}
+	})
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bitrise-io/go-steputils/tools"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/client"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/model"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/deployer"
+)
+
+const (
+	failModeFailFast = "fail_fast"
+	failModeContinue = "continue"
+)
+
+func newDeployerConfig(cfg config) deployer.Config {
+	return deployer.Config{
+		AppPath:     cfg.AppPath,
+		MappingPath: cfg.MappingPath,
+		AllGroups:   cfg.DistributeAllGroup,
+		Groups:      deployer.SplitLines(cfg.DistributionGroup),
+		Stores:      deployer.SplitLines(cfg.DistributionStore),
+		Testers:     deployer.SplitLines(cfg.DistributionTester),
+		Concurrency: cfg.DistributionConcurrency,
+		FailFast:    cfg.FailMode != failModeContinue,
+	}
+}
+
+// appCenterDeps implements the App Center dependencies of the deployer with the App Center client.
+// The app's groups are fetched before the deploy starts, so they are served from memory.
+type appCenterDeps struct {
+	api            client.API
+	releaseOptions model.ReleaseOptions
+	groups         []model.Group
+}
+
+func (d appCenterDeps) releaseAPI(release model.Release) appcenter.ReleaseAPI {
+	return appcenter.CreateReleaseAPI(d.api, release, d.releaseOptions)
+}
+
+func (d appCenterDeps) CreateRelease(_ context.Context, appPath string) (model.Release, error) {
+	opts := d.releaseOptions
+	opts.FilePath = appPath
+
+	return appcenter.CreateApplicationAPI(d.api, opts).NewRelease()
+}
+
+func (d appCenterDeps) Groups(context.Context) ([]model.Group, error) {
+	return d.groups, nil
+}
+
+func (d appCenterDeps) Store(_ context.Context, name string) (model.Store, error) {
+	return appcenter.CreateApplicationAPI(d.api, d.releaseOptions).Stores(name)
+}
+
+func (d appCenterDeps) AddGroup(_ context.Context, release model.Release, group model.Group) error {
+	return d.releaseAPI(release).AddGroup(group)
+}
+
+func (d appCenterDeps) AddStore(_ context.Context, release model.Release, store model.Store) error {
+	return d.releaseAPI(release).AddStore(store)
+}
+
+func (d appCenterDeps) AddTester(_ context.Context, release model.Release, email string) error {
+	return d.releaseAPI(release).AddTester(email)
+}
+
+func (d appCenterDeps) UploadSymbol(_ context.Context, release model.Release, path string) error {
+	return d.releaseAPI(release).UploadSymbol(path)
+}
+
+// releasePreparer checks the new release against the previous one, then sets its build metadata and release notes.
+type releasePreparer struct {
+	cfg             config
+	deps            appCenterDeps
+	notes           releaseNotesInput
+	previousRelease model.Release
+	report          *deployReport
+
+	// releaseNotes are the rendered release notes, set by PrepareRelease.
+	releaseNotes string
+}
+
+func (p *releasePreparer) PrepareRelease(_ context.Context, release model.Release) error {
+	log.Infof("Comparing with the previous release")
+
+	// The comparison is exported even without a previous release, so later steps always get a defined value.
+	comparison := compareReleases(p.previousRelease, release, newReleaseBudget(p.cfg))
+	if p.previousRelease.ID != 0 {
+		comparison.print()
+		p.report.Comparison = &comparison
+	} else {
+		log.Printf("- No previous release, nothing to compare")
+	}
+
+	comparisonJSON, err := comparison.json()
+	if err != nil {
+		return fmt.Errorf("failed to serialize the release comparison: %s", err)
+	}
+	if err := tools.ExportEnvironmentWithEnvman(releaseComparisonEnvKey, comparisonJSON); err != nil {
+		return fmt.Errorf("failed to export environment variable: %s with value: %s. Error: %s", releaseComparisonEnvKey, comparisonJSON, err)
+	}
+
+	releaseAPI := p.deps.releaseAPI(release)
+
+	if len(comparison.Violations) > 0 {
+		return rejectRelease(releaseAPI, release, comparison.Violations)
+	}
+
+	log.Donef("- Done")
+	fmt.Println()
+
+	if build := newReleaseBuild(p.cfg); !isEmptyBuild(build) {
+		log.Infof("Setting build metadata")
+		log.Printf("- Branch: %s", build.BranchName)
+		log.Printf("- Commit: %s", build.CommitHash)
+		log.Printf("- Message: %s", build.CommitMessage)
+		phaseDone := p.report.Phase("build metadata")
+
+		if err := setReleaseBuild(releaseAPI, build); err != nil {
+			log.Warnf("Failed to set build metadata on the release, error: %s", err)
+		} else {
+			log.Donef("- Done")
+		}
+
+		phaseDone()
+		fmt.Println()
+	}
+
+	releaseNotes, err := p.notes.render(release)
+	if err != nil {
+		return fmt.Errorf("failed to prepare release notes: %s", err)
+	}
+
+	releaseNotes, truncated := truncateReleaseNotes(releaseNotes, releaseNotesMaxLength)
+	if truncated {
+		log.Warnf("Release notes are longer than %d characters, truncating them", releaseNotesMaxLength)
+	}
+	p.releaseNotes = releaseNotes
+
+	// Release notes can only be modified after a release has been created (using separate endpoints),
+	// and the notification email is generated when the release is added to a destination.
+	// To make sure the email contains the release notes, the notes are set first and read back
+	// until App Center returns them, only then is the release distributed.
+	if len(releaseNotes) > 0 {
+		log.Infof("Setting release notes")
+		phaseDone := p.report.Phase("release notes")
+
+		if err := releaseAPI.SetReleaseNote(releaseNotes); err != nil {
+			return fmt.Errorf("failed to set release note: %w", err)
+		}
+
+		if p.cfg.NotifyTesters {
+			log.Printf("Waiting for the release notes to be saved before notifying testers")
+			if err := waitForReleaseNotes(releaseAPI, releaseNotes); err != nil {
+				return fmt.Errorf("release notes are not in place, not distributing the release to avoid notifications without release notes: %w", err)
+			}
+		}
+
+		phaseDone()
+
+		log.Donef("- Done")
+		fmt.Println()
+	}
+
+	return nil
+}
+
+// rejectRelease disables the uploaded release exceeding the budget, so it can not be installed
+// from App Center, and returns the error failing the step.
+func rejectRelease(releaseAPI appcenter.ReleaseAPI, release model.Release, violations []string) error {
+	budgetErr := strings.Join(violations, "; ")
+
+	log.Warnf("Release (%d) exceeds the budget, disabling it", release.ID)
+	if err := releaseAPI.SetEnabled(false); err != nil {
+		return fmt.Errorf("release (%d) is uploaded but not distributed, it exceeds the budget: %s. Disabling it failed, it is still enabled in App Center: %w", release.ID, budgetErr, err)
+	}
+
+	return fmt.Errorf("release (%d) is uploaded but disabled and not distributed, it exceeds the budget: %s", release.ID, budgetErr)
+}
+
+// releaseOutputExporter exports the outputs of the deployed release.
+type releaseOutputExporter struct {
+	cfg       config
+	urls      appURLs
+	preparer  *releasePreparer
+	reporting runReporting
+}
+
+func (e releaseOutputExporter) ExportOutputs(_ context.Context, result deployer.Result) error {
+	// The outputs are exported together with the report, so it has to be complete by now.
+	e.reporting.report.setResult(result)
+
+	return exportReleaseOutputs(e.cfg, e.reporting, result, e.urls, e.preparer.releaseNotes)
+}
+
+// deploy uploads the artifact as a new release and distributes it.
+func deploy(cfg config, api client.API, releaseOptions model.ReleaseOptions, groups []model.Group, urls appURLs, notes releaseNotesInput, previousRelease model.Release, reporting runReporting) {
+	deps := appCenterDeps{
+		api:            api,
+		releaseOptions: releaseOptions,
+		groups:         groups,
+	}
+	preparer := &releasePreparer{
+		cfg:             cfg,
+		deps:            deps,
+		notes:           notes,
+		previousRelease: previousRelease,
+		report:          reporting.report,
+	}
+
+	d := deployer.Deployer{
+		Creator:     deps,
+		Preparer:    preparer,
+		Resolver:    deps,
+		Distributor: deps,
+		Symbols:     deps,
+		Exporter:    releaseOutputExporter{cfg: cfg, urls: urls, preparer: preparer, reporting: reporting},
+		Phases:      reporting.report,
+	}
+
+	result, err := d.Deploy(context.Background(), newDeployerConfig(cfg))
+	reporting.report.setResult(result)
+	if err != nil {
+		failf("Deploy failed, error: %s", err)
+	}
+
+	exitOnPartialDistribution(cfg, result.Summary, reporting.guide)
+}
+
+// exitOnPartialDistribution advises on the failed destinations,
+// then exits with an error if a destination failed and fail_on_partial_distribution is enabled.
+func exitOnPartialDistribution(cfg config, summary deployer.Summary, guide errorGuide) {
+	failedDestinations := summary.Failed()
+	if len(failedDestinations) == 0 {
+		return
+	}
+
+	log.Warnf("Release was distributed with %d failed destination(s)", len(failedDestinations))
+	for _, failed := range failedDestinations {
+		if advice := guide.advise(failed.Err()); advice != "" {
+			log.Warnf("- %s %s: %s", failed.Type, failed.Name, advice)
+		}
+	}
+
+	if cfg.FailOnPartial {
+		log.Errorf("Failing the step as fail_on_partial_distribution is enabled")
+		os.Exit(1)
+	}
+}
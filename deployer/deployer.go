@@ -0,0 +1,174 @@
+// Package deployer implements the deploy flow of the step: uploading the artifact as a new release,
+// preparing it, uploading the mapping file, distributing it and exporting the outputs.
+//
+// Every App Center call goes through the interfaces of the Deployer, so the flow can be exercised with fakes.
+package deployer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/model"
+)
+
+// Statuses of a deploy.
+const (
+	StatusSuccess = "success"
+	StatusPartial = "partial"
+)
+
+// ReleaseCreator uploads the artifact and returns the created release.
+type ReleaseCreator interface {
+	CreateRelease(ctx context.Context, appPath string) (model.Release, error)
+}
+
+// ReleasePreparer runs after the release is created and before it is distributed,
+// for example to check it or to set its metadata and release notes.
+type ReleasePreparer interface {
+	PrepareRelease(ctx context.Context, release model.Release) error
+}
+
+// Resolver looks up the distribution groups and stores of the app.
+type Resolver interface {
+	Groups(ctx context.Context) ([]model.Group, error)
+	Store(ctx context.Context, name string) (model.Store, error)
+}
+
+// Distributor adds a release to a destination.
+type Distributor interface {
+	AddGroup(ctx context.Context, release model.Release, group model.Group) error
+	AddStore(ctx context.Context, release model.Release, store model.Store) error
+	AddTester(ctx context.Context, release model.Release, email string) error
+}
+
+// SymbolUploader uploads the mapping file of a release.
+type SymbolUploader interface {
+	UploadSymbol(ctx context.Context, release model.Release, path string) error
+}
+
+// OutputExporter exports the outputs of a finished deploy.
+type OutputExporter interface {
+	ExportOutputs(ctx context.Context, result Result) error
+}
+
+// PhaseTracker measures the phases of the deploy, the function returned by Phase ends the phase.
+type PhaseTracker interface {
+	Phase(name string) func()
+}
+
+// Config is the configuration of a deploy.
+type Config struct {
+	AppPath     string
+	MappingPath string
+
+	// AllGroups distributes the release to every group of the app, Groups is ignored if set.
+	AllGroups bool
+	Groups    []string
+	Stores    []string
+	Testers   []string
+
+	// Concurrency is the number of destinations the release is added to in parallel.
+	// It is ignored in fail fast mode, as App Center calls in progress cannot be cancelled.
+	Concurrency int
+	// FailFast stops the distribution and fails the deploy at the first failed destination,
+	// adding the release to one destination at a time.
+	// Otherwise every destination is tried and the deploy is partial.
+	FailFast bool
+}
+
+// Result is the outcome of a deploy.
+type Result struct {
+	Status  string
+	Release model.Release
+	Plan    Plan
+	Summary Summary
+	// SymbolUpload is the outcome of the mapping file upload, nil if no mapping file was uploaded.
+	SymbolUpload *SymbolUploadResult
+}
+
+// SymbolUploadResult is the outcome of uploading the mapping file of a release.
+type SymbolUploadResult struct {
+	Path string
+	Err  error
+}
+
+// Deployer runs the deploy flow using its dependencies.
+// Preparer and Phases are optional.
+type Deployer struct {
+	Creator     ReleaseCreator
+	Preparer    ReleasePreparer
+	Resolver    Resolver
+	Distributor Distributor
+	Symbols     SymbolUploader
+	Exporter    OutputExporter
+	Phases      PhaseTracker
+}
+
+// Deploy uploads the artifact, prepares the release, uploads the mapping file, distributes the release and exports the outputs.
+// The returned result contains everything done until an error occurred.
+func (d Deployer) Deploy(ctx context.Context, cfg Config) (Result, error) {
+	var result Result
+
+	groups, err := d.Resolver.Groups(ctx)
+	if err != nil {
+		return result, fmt.Errorf("failed to fetch groups: %w", err)
+	}
+	result.Plan = NewPlan(cfg, groups)
+
+	log.Infof("Uploading binary")
+	phaseDone := d.phase("upload")
+
+	release, err := d.Creator.CreateRelease(ctx, cfg.AppPath)
+	if err != nil {
+		return result, fmt.Errorf("failed to create new release: %w", err)
+	}
+	result.Release = release
+
+	phaseDone()
+	log.Donef("- Done")
+	fmt.Println()
+
+	if d.Preparer != nil {
+		if err := d.Preparer.PrepareRelease(ctx, release); err != nil {
+			return result, err
+		}
+	}
+
+	if len(cfg.MappingPath) > 0 {
+		log.Infof("Uploading mapping file")
+		phaseDone = d.phase("mapping upload")
+
+		err := d.Symbols.UploadSymbol(ctx, release, cfg.MappingPath)
+		result.SymbolUpload = &SymbolUploadResult{Path: cfg.MappingPath, Err: err}
+		if err != nil {
+			return result, fmt.Errorf("failed to upload symbol file(%s): %w", cfg.MappingPath, err)
+		}
+
+		phaseDone()
+		log.Donef("- Done")
+		fmt.Println()
+	}
+
+	summary, err := d.Distribute(ctx, cfg, release, result.Plan)
+	result.Summary = summary
+	if err != nil {
+		return result, err
+	}
+
+	result.Status = summary.Status()
+
+	if err := d.Exporter.ExportOutputs(ctx, result); err != nil {
+		return result, fmt.Errorf("failed to export outputs: %w", err)
+	}
+
+	return result, nil
+}
+
+func (d Deployer) phase(name string) func() {
+	if d.Phases == nil {
+		return func() {}
+	}
+
+	return d.Phases.Phase(name)
+}
@@ -0,0 +1,312 @@
+package deployer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/model"
+)
+
+// fakeAppCenter implements every dependency of the Deployer in memory.
+type fakeAppCenter struct {
+	groups     []model.Group
+	groupsErr  error
+	createErr  error
+	prepareErr error
+	symbolErr  error
+	stores     []model.Store
+	// failing are the destinations AddGroup, AddStore and AddTester fail for, by name.
+	failing map[string]bool
+
+	mu       sync.Mutex
+	created  []string
+	prepared []int
+	added    []string
+	symbols  []string
+	exported []Result
+}
+
+func (f *fakeAppCenter) CreateRelease(_ context.Context, appPath string) (model.Release, error) {
+	f.created = append(f.created, appPath)
+	if f.createErr != nil {
+		return model.Release{}, f.createErr
+	}
+
+	return model.Release{ID: 42, Version: "1", ShortVersion: "1.0"}, nil
+}
+
+func (f *fakeAppCenter) PrepareRelease(_ context.Context, release model.Release) error {
+	f.prepared = append(f.prepared, release.ID)
+
+	return f.prepareErr
+}
+
+func (f *fakeAppCenter) Groups(context.Context) ([]model.Group, error) {
+	return f.groups, f.groupsErr
+}
+
+func (f *fakeAppCenter) Store(_ context.Context, name string) (model.Store, error) {
+	for _, store := range f.stores {
+		if store.Name == name {
+			return store, nil
+		}
+	}
+
+	return model.Store{}, fmt.Errorf("store %s not found", name)
+}
+
+func (f *fakeAppCenter) add(destinationType DestinationType, name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.failing[name] {
+		return fmt.Errorf("failed to add %s", name)
+	}
+
+	f.added = append(f.added, fmt.Sprintf("%s:%s", destinationType, name))
+
+	return nil
+}
+
+func (f *fakeAppCenter) AddGroup(_ context.Context, _ model.Release, group model.Group) error {
+	return f.add(DestinationTypeGroup, group.Name)
+}
+
+func (f *fakeAppCenter) AddStore(_ context.Context, _ model.Release, store model.Store) error {
+	return f.add(DestinationTypeStore, store.Name)
+}
+
+func (f *fakeAppCenter) AddTester(_ context.Context, _ model.Release, email string) error {
+	return f.add(DestinationTypeTester, email)
+}
+
+func (f *fakeAppCenter) UploadSymbol(_ context.Context, _ model.Release, path string) error {
+	f.symbols = append(f.symbols, path)
+
+	return f.symbolErr
+}
+
+func (f *fakeAppCenter) ExportOutputs(_ context.Context, result Result) error {
+	f.exported = append(f.exported, result)
+
+	return nil
+}
+
+func (f *fakeAppCenter) deployer() Deployer {
+	return Deployer{
+		Creator:     f,
+		Preparer:    f,
+		Resolver:    f,
+		Distributor: f,
+		Symbols:     f,
+		Exporter:    f,
+	}
+}
+
+func groupNamesOf(groups []model.Group) []string {
+	var names []string
+	for _, group := range groups {
+		names = append(names, group.Name)
+	}
+
+	return names
+}
+
+func TestDeployer_Deploy(t *testing.T) {
+	appGroups := []model.Group{
+		{ID: "1", Name: "collaborators", DisplayName: "Collaborators"},
+		{ID: "2", Name: "public", DisplayName: "Public", IsPublic: true},
+		{ID: "3", Name: "beta", DisplayName: "Beta"},
+	}
+
+	tests := []struct {
+		name        string
+		cfg         Config
+		fake        *fakeAppCenter
+		wantErr     bool
+		wantStatus  string
+		wantAdded   []string
+		wantFailed  []string
+		wantPublic  []string
+		wantSymbols []string
+	}{
+		{
+			name:       "configured groups",
+			cfg:        Config{AppPath: "app.apk", Groups: []string{"Collaborators", "public"}, FailFast: true},
+			fake:       &fakeAppCenter{},
+			wantStatus: StatusSuccess,
+			wantAdded:  []string{"group:collaborators", "group:public"},
+			wantPublic: []string{"public"},
+		},
+		{
+			name:       "all groups ignores the configured groups",
+			cfg:        Config{AppPath: "app.apk", AllGroups: true, Groups: []string{"Beta"}, FailFast: true},
+			fake:       &fakeAppCenter{},
+			wantStatus: StatusSuccess,
+			wantAdded:  []string{"group:beta", "group:collaborators", "group:public"},
+			wantPublic: []string{"public"},
+		},
+		{
+			name:       "stores and testers",
+			cfg:        Config{AppPath: "app.apk", Stores: []string{"Production"}, Testers: []string{"tester@example.com"}, FailFast: true},
+			fake:       &fakeAppCenter{stores: []model.Store{{ID: "s1", Name: "Production"}}},
+			wantStatus: StatusSuccess,
+			wantAdded:  []string{"store:Production", "tester:tester@example.com"},
+		},
+		{
+			name:        "mapping file",
+			cfg:         Config{AppPath: "app.apk", MappingPath: "mapping.txt", Groups: []string{"Beta"}, FailFast: true},
+			fake:        &fakeAppCenter{},
+			wantStatus:  StatusSuccess,
+			wantAdded:   []string{"group:beta"},
+			wantSymbols: []string{"mapping.txt"},
+		},
+		{
+			name:        "failed mapping file upload stops the deploy",
+			cfg:         Config{AppPath: "app.apk", MappingPath: "mapping.txt", Groups: []string{"Beta"}, FailFast: true},
+			fake:        &fakeAppCenter{symbolErr: errors.New("upload failed")},
+			wantErr:     true,
+			wantSymbols: []string{"mapping.txt"},
+		},
+		{
+			name: "continue mode tries every destination",
+			cfg: Config{
+				AppPath:  "app.apk",
+				Groups:   []string{"Collaborators", "Missing", "Public"},
+				Stores:   []string{"Unknown store"},
+				Testers:  []string{"bad@example.com", "tester@example.com"},
+				FailFast: false,
+			},
+			fake:       &fakeAppCenter{failing: map[string]bool{"bad@example.com": true}},
+			wantStatus: StatusPartial,
+			wantAdded:  []string{"group:collaborators", "group:public", "tester:tester@example.com"},
+			wantFailed: []string{"Missing", "Unknown store", "bad@example.com"},
+			wantPublic: []string{"public"},
+		},
+		{
+			name:       "fail fast mode stops at the first failed destination",
+			cfg:        Config{AppPath: "app.apk", Groups: []string{"Collaborators"}, Testers: []string{"bad@example.com", "tester@example.com"}, Concurrency: 1, FailFast: true},
+			fake:       &fakeAppCenter{failing: map[string]bool{"bad@example.com": true}},
+			wantErr:    true,
+			wantAdded:  []string{"group:collaborators"},
+			wantFailed: []string{"bad@example.com", "tester@example.com"},
+		},
+		{
+			name: "fail fast mode adds no destination after the first failure with the default concurrency",
+			cfg: Config{
+				AppPath:     "app.apk",
+				Groups:      []string{"Collaborators"},
+				Testers:     []string{"bad@example.com", "a@example.com", "b@example.com", "c@example.com"},
+				Concurrency: 4,
+				FailFast:    true,
+			},
+			fake:       &fakeAppCenter{failing: map[string]bool{"bad@example.com": true}},
+			wantErr:    true,
+			wantAdded:  []string{"group:collaborators"},
+			wantFailed: []string{"bad@example.com", "a@example.com", "b@example.com", "c@example.com"},
+		},
+		{
+			name:    "failed groups lookup does not upload",
+			cfg:     Config{AppPath: "app.apk", Groups: []string{"Collaborators"}, FailFast: true},
+			fake:    &fakeAppCenter{groupsErr: errors.New("unauthorized")},
+			wantErr: true,
+		},
+		{
+			name:    "failed upload",
+			cfg:     Config{AppPath: "app.apk", Groups: []string{"Collaborators"}, FailFast: true},
+			fake:    &fakeAppCenter{createErr: errors.New("upload failed")},
+			wantErr: true,
+		},
+		{
+			name:    "failed preparation does not distribute",
+			cfg:     Config{AppPath: "app.apk", Groups: []string{"Collaborators"}, FailFast: true},
+			fake:    &fakeAppCenter{prepareErr: errors.New("over budget")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.fake.groups == nil {
+				tt.fake.groups = appGroups
+			}
+
+			result, err := tt.fake.deployer().Deploy(context.Background(), tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Deploy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if result.Status != tt.wantStatus {
+				t.Errorf("Deploy() status = %q, want %q", result.Status, tt.wantStatus)
+			}
+			if plan := NewPlan(tt.cfg, tt.fake.groups); tt.fake.groupsErr == nil && !reflect.DeepEqual(result.Plan, plan) {
+				t.Errorf("Deploy() plan = %+v, want %+v", result.Plan, plan)
+			}
+
+			sort.Strings(tt.fake.added)
+			if !reflect.DeepEqual(tt.fake.added, tt.wantAdded) {
+				t.Errorf("added destinations = %v, want %v", tt.fake.added, tt.wantAdded)
+			}
+
+			var failed []string
+			for _, destination := range result.Summary.Failed() {
+				failed = append(failed, destination.Name)
+			}
+			if !reflect.DeepEqual(failed, tt.wantFailed) {
+				t.Errorf("failed destinations = %v, want %v", failed, tt.wantFailed)
+			}
+
+			if got := groupNamesOf(result.Summary.PublicGroups); !reflect.DeepEqual(got, tt.wantPublic) {
+				t.Errorf("public groups = %v, want %v", got, tt.wantPublic)
+			}
+
+			if !reflect.DeepEqual(tt.fake.symbols, tt.wantSymbols) {
+				t.Errorf("symbol uploads = %v, want %v", tt.fake.symbols, tt.wantSymbols)
+			}
+			if len(tt.wantSymbols) > 0 {
+				if result.SymbolUpload == nil || result.SymbolUpload.Path != tt.wantSymbols[0] || (result.SymbolUpload.Err != nil) != (tt.fake.symbolErr != nil) {
+					t.Errorf("Deploy() symbol upload = %+v, want the upload of %s", result.SymbolUpload, tt.wantSymbols[0])
+				}
+			} else if result.SymbolUpload != nil {
+				t.Errorf("Deploy() symbol upload = %+v, want nil", result.SymbolUpload)
+			}
+
+			if tt.wantErr {
+				if len(tt.fake.exported) > 0 {
+					t.Errorf("outputs exported for a failed deploy")
+				}
+				if tt.fake.groupsErr != nil && len(tt.fake.created) > 0 {
+					t.Errorf("created = %v, want no upload without the groups", tt.fake.created)
+				}
+				return
+			}
+
+			if len(tt.fake.exported) != 1 || !reflect.DeepEqual(tt.fake.exported[0], result) {
+				t.Errorf("exported results = %+v, want the result once", tt.fake.exported)
+			}
+			if !reflect.DeepEqual(tt.fake.created, []string{"app.apk"}) || !reflect.DeepEqual(tt.fake.prepared, []int{42}) {
+				t.Errorf("created = %v, prepared = %v, want the release created and prepared once", tt.fake.created, tt.fake.prepared)
+			}
+		})
+	}
+}
+
+func TestDeployer_Distribute_unknownGroup(t *testing.T) {
+	fake := &fakeAppCenter{}
+	cfg := Config{Groups: []string{"Nope"}, FailFast: true}
+	plan := NewPlan(cfg, []model.Group{{Name: "beta", DisplayName: "Beta"}})
+
+	_, err := fake.deployer().Distribute(context.Background(), cfg, model.Release{ID: 1}, plan)
+
+	var notFound *GroupNotFoundError
+	if !errors.As(err, &notFound) {
+		t.Fatalf("Distribute() error = %v, want a GroupNotFoundError", err)
+	}
+	if notFound.Name != "Nope" || !reflect.DeepEqual(notFound.Available, []string{"Beta"}) {
+		t.Errorf("Distribute() error = %+v, want the unknown group and the available ones", notFound)
+	}
+}
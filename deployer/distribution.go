@@ -0,0 +1,357 @@
+package deployer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/model"
+	"golang.org/x/sync/semaphore"
+)
+
+// DestinationType is the kind of destination a release is distributed to, as reported in the failed destinations output.
+type DestinationType string
+
+// Destination types of a distribution.
+const (
+	DestinationTypeGroup  DestinationType = "group"
+	DestinationTypeStore  DestinationType = "store"
+	DestinationTypeTester DestinationType = "tester"
+)
+
+// destination is a single group, store or tester the release is added to.
+type destination struct {
+	Type DestinationType
+	Name string
+	add  func(ctx context.Context) error
+}
+
+// DestinationResult is the outcome of adding the release to a destination.
+type DestinationResult struct {
+	Type  DestinationType `json:"type"`
+	Name  string          `json:"name"`
+	Error string          `json:"error,omitempty"`
+
+	err error
+}
+
+// Err returns the error the release could not be added to the destination with, nil if it was added.
+func (r DestinationResult) Err() error {
+	return r.err
+}
+
+// Summary collects the outcome of every destination the release was distributed to.
+type Summary struct {
+	Results []DestinationResult
+	// PublicGroups are the public groups the release was successfully added to.
+	PublicGroups []model.Group
+}
+
+// Failed returns the destinations the release could not be added to.
+func (s Summary) Failed() []DestinationResult {
+	failed := []DestinationResult{}
+	for _, result := range s.Results {
+		if result.Error != "" {
+			failed = append(failed, result)
+		}
+	}
+
+	return failed
+}
+
+// Status returns StatusPartial if the release could not be added to a destination, StatusSuccess otherwise.
+func (s Summary) Status() string {
+	if len(s.Failed()) > 0 {
+		return StatusPartial
+	}
+
+	return StatusSuccess
+}
+
+// SucceededNames returns the names of the destinations the release was added to.
+func (s Summary) SucceededNames() []string {
+	var names []string
+	for _, result := range s.Results {
+		if result.Error == "" {
+			names = append(names, result.Name)
+		}
+	}
+
+	return names
+}
+
+// FailedJSON returns the failed destinations as a JSON array.
+func (s Summary) FailedJSON() (string, error) {
+	b, err := json.Marshal(s.Failed())
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// Print logs the outcome of every destination.
+func (s Summary) Print() {
+	log.Infof("Distribution summary")
+
+	if len(s.Results) == 0 {
+		log.Printf("- no destinations")
+		return
+	}
+
+	for _, result := range s.Results {
+		status := "ok"
+		if result.Error != "" {
+			status = fmt.Sprintf("failed: %s", result.Error)
+		}
+
+		log.Printf("- %-6s  %-30s  %s", result.Type, result.Name, status)
+	}
+}
+
+var errDistributionCancelled = errors.New("skipped, another destination failed")
+
+// distribute adds the release to the given destinations using at most concurrency parallel workers.
+// The results are returned in the order of the destinations, independently of the order the workers finished in.
+// If failFast is set, destinations which were not started before the first failure are skipped.
+func distribute(ctx context.Context, destinations []destination, concurrency int, failFast bool) []DestinationResult {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make([]error, len(destinations))
+	sem := semaphore.NewWeighted(int64(concurrency))
+	var wg sync.WaitGroup
+
+	for idx, dest := range destinations {
+		if err := sem.Acquire(ctx, 1); err != nil || ctx.Err() != nil {
+			if err == nil {
+				sem.Release(1)
+			}
+
+			for i := idx; i < len(destinations); i++ {
+				errs[i] = errDistributionCancelled
+			}
+			break
+		}
+
+		wg.Add(1)
+		go func(idx int, dest destination) {
+			defer wg.Done()
+			defer sem.Release(1)
+
+			errs[idx] = dest.add(ctx)
+			if errs[idx] != nil && failFast {
+				cancel()
+			}
+		}(idx, dest)
+	}
+
+	wg.Wait()
+
+	results := make([]DestinationResult, len(destinations))
+	for idx, dest := range destinations {
+		results[idx] = DestinationResult{
+			Type: dest.Type,
+			Name: dest.Name,
+		}
+		if errs[idx] != nil {
+			results[idx].Error = errs[idx].Error()
+			results[idx].err = errs[idx]
+		}
+	}
+
+	return results
+}
+
+// FindGroup looks up a distribution group by its name or display name.
+func FindGroup(groups []model.Group, name string) (model.Group, bool) {
+	for _, group := range groups {
+		if group.Name == name || strings.EqualFold(group.DisplayName, name) {
+			return group, true
+		}
+	}
+
+	return model.Group{}, false
+}
+
+// GroupNotFoundError is returned for a configured group the app has no distribution group with.
+type GroupNotFoundError struct {
+	Name string
+	// Available are the display names of the app's groups.
+	Available []string
+}
+
+func (e *GroupNotFoundError) Error() string {
+	return "group not found"
+}
+
+// LookupGroup looks up a distribution group like FindGroup, returning a GroupNotFoundError if the app has no such group.
+func LookupGroup(groups []model.Group, name string) (model.Group, error) {
+	if group, ok := FindGroup(groups, name); ok {
+		return group, nil
+	}
+
+	return model.Group{}, &GroupNotFoundError{Name: name, Available: groupNames(groups)}
+}
+
+func groupNames(groups []model.Group) []string {
+	var names []string
+	for _, group := range groups {
+		names = append(names, group.DisplayName)
+	}
+
+	return names
+}
+
+// PlannedGroup is a configured distribution group, resolved against the app's groups.
+type PlannedGroup struct {
+	Name  string
+	Group model.Group
+	Found bool
+}
+
+// Plan lists every destination the release should be added to, in the configured order.
+type Plan struct {
+	Groups  []PlannedGroup
+	Stores  []string
+	Testers []string
+	// AvailableGroups are the display names of the app's groups.
+	AvailableGroups []string
+}
+
+// NotFoundError returns the error of a planned group the app does not have.
+func (p Plan) NotFoundError(planned PlannedGroup) error {
+	return &GroupNotFoundError{Name: planned.Name, Available: p.AvailableGroups}
+}
+
+// NewPlan resolves the configured destinations against the app's groups.
+func NewPlan(cfg Config, groups []model.Group) Plan {
+	var plan Plan
+
+	if cfg.AllGroups {
+		for _, group := range groups {
+			plan.Groups = append(plan.Groups, PlannedGroup{Name: group.DisplayName, Group: group, Found: true})
+		}
+	} else {
+		for _, groupName := range cfg.Groups {
+			group, ok := FindGroup(groups, groupName)
+			plan.Groups = append(plan.Groups, PlannedGroup{Name: groupName, Group: group, Found: ok})
+		}
+	}
+
+	plan.Stores = cfg.Stores
+	plan.Testers = cfg.Testers
+	plan.AvailableGroups = groupNames(groups)
+
+	return plan
+}
+
+// SplitLines returns the trimmed, non-empty lines of a multiline input.
+func SplitLines(value string) []string {
+	var lines []string
+	for _, line := range strings.Split(value, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+// Distribute adds the release to every destination of the plan and prints the distribution summary.
+// In fail fast mode, the first failed destination is returned as an error together with the summary.
+func (d Deployer) Distribute(ctx context.Context, cfg Config, release model.Release, plan Plan) (Summary, error) {
+	var destinations []destination
+
+	for _, planned := range plan.Groups {
+		planned := planned
+		destinations = append(destinations, destination{
+			Type: DestinationTypeGroup,
+			Name: planned.Name,
+			add: func(ctx context.Context) error {
+				if !planned.Found {
+					return plan.NotFoundError(planned)
+				}
+
+				return d.Distributor.AddGroup(ctx, release, planned.Group)
+			},
+		})
+	}
+
+	for _, storeName := range plan.Stores {
+		storeName := storeName
+		destinations = append(destinations, destination{
+			Type: DestinationTypeStore,
+			Name: storeName,
+			add: func(ctx context.Context) error {
+				store, err := d.Resolver.Store(ctx, storeName)
+				if err != nil {
+					return fmt.Errorf("failed to fetch store: %w", err)
+				}
+
+				return d.Distributor.AddStore(ctx, release, store)
+			},
+		})
+	}
+
+	for _, email := range plan.Testers {
+		email := email
+		destinations = append(destinations, destination{
+			Type: DestinationTypeTester,
+			Name: email,
+			add: func(ctx context.Context) error {
+				return d.Distributor.AddTester(ctx, release, email)
+			},
+		})
+	}
+
+	// A started App Center call cannot be cancelled, so in fail fast mode a single destination is added at a time
+	// to not add the release to further destinations after the first failure.
+	concurrency := cfg.Concurrency
+	if cfg.FailFast {
+		concurrency = 1
+	}
+
+	log.Infof("Distributing the release to %d destination(s), concurrency: %d", len(destinations), concurrency)
+
+	for _, dest := range destinations {
+		log.Printf("- %s: %s", dest.Type, dest.Name)
+	}
+
+	phaseDone := d.phase("distribution")
+
+	summary := Summary{
+		Results: distribute(ctx, destinations, concurrency, cfg.FailFast),
+	}
+
+	phaseDone()
+
+	log.Donef("- Done")
+	fmt.Println()
+
+	for idx, planned := range plan.Groups {
+		if summary.Results[idx].Error == "" && planned.Group.IsPublic {
+			summary.PublicGroups = append(summary.PublicGroups, planned.Group)
+		}
+	}
+
+	summary.Print()
+	fmt.Println()
+
+	if failed := summary.Failed(); len(failed) > 0 && cfg.FailFast {
+		return summary, fmt.Errorf("failed to add %s(%s) to the release, error: %w", failed[0].Type, failed[0].Name, failed[0].err)
+	}
+
+	return summary, nil
+}
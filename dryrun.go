@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/model"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/deployer"
+)
+
+const dryRunStatus = "dry_run"
+
+// dryRun validates the inputs and resolves every destination without creating a release,
+// then prints what a real run would do and exports the outputs which are known upfront.
+func dryRun(cfg config, appAPI appcenter.AppAPI, urls appURLs, groups []model.Group, plan deployer.Plan, notes releaseNotesInput) {
+	log.Infof("Validating inputs (dry run)")
+
+	var problems []string
+
+	appInfo, err := os.Stat(cfg.AppPath)
+	switch {
+	case err != nil:
+		problems = append(problems, fmt.Sprintf("app_path: %s", err))
+	case appInfo.IsDir():
+		problems = append(problems, fmt.Sprintf("app_path: %s is a directory", cfg.AppPath))
+	case appInfo.Size() == 0:
+		problems = append(problems, fmt.Sprintf("app_path: %s is empty", cfg.AppPath))
+	}
+
+	if ext := strings.ToLower(filepath.Ext(cfg.AppPath)); ext != ".apk" && ext != ".aab" {
+		problems = append(problems, fmt.Sprintf("app_path: unsupported artifact type (%s), expected an .apk or .aab", ext))
+	}
+
+	if len(cfg.MappingPath) > 0 {
+		if mappingInfo, err := os.Stat(cfg.MappingPath); err != nil {
+			problems = append(problems, fmt.Sprintf("mapping_path: %s", err))
+		} else if mappingInfo.IsDir() || mappingInfo.Size() == 0 {
+			problems = append(problems, fmt.Sprintf("mapping_path: %s is not a non-empty file", cfg.MappingPath))
+		}
+	}
+
+	var publicGroups []model.Group
+	for _, planned := range plan.Groups {
+		if !planned.Found {
+			problems = append(problems, fmt.Sprintf("distribution group not found: %s. %s", planned.Name, availableGroups(plan.AvailableGroups)))
+			continue
+		}
+
+		if planned.Group.IsPublic {
+			publicGroups = append(publicGroups, planned.Group)
+		}
+	}
+
+	if _, err := comparedGroup(cfg, groups, plan); err != nil {
+		problems = append(problems, fmt.Sprintf("%s. %s", err, availableGroups(plan.AvailableGroups)))
+	}
+
+	for _, storeName := range plan.Stores {
+		if _, err := appAPI.Stores(storeName); err != nil {
+			problems = append(problems, fmt.Sprintf("distribution store (%s): %s", storeName, err))
+		}
+	}
+
+	for _, email := range plan.Testers {
+		if _, err := mail.ParseAddress(email); err != nil {
+			problems = append(problems, fmt.Sprintf("tester (%s): invalid email address: %s", email, err))
+		}
+	}
+
+	// The release is not created, so the template is rendered without the version App Center would report.
+	releaseNotes, err := notes.render(model.Release{})
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("release notes: %s", err))
+	}
+
+	releaseNotes, truncated := truncateReleaseNotes(releaseNotes, releaseNotesMaxLength)
+
+	log.Donef("- Done")
+	fmt.Println()
+
+	log.Infof("Deploy plan")
+	log.Printf("- App: %s/%s", cfg.OwnerName, cfg.AppName)
+	if appInfo != nil {
+		log.Printf("- Upload: %s (%d bytes)", cfg.AppPath, appInfo.Size())
+	} else {
+		log.Printf("- Upload: %s", cfg.AppPath)
+	}
+	if len(cfg.MappingPath) > 0 {
+		log.Printf("- Mapping file: %s", cfg.MappingPath)
+	}
+	log.Printf("- Release notes: %d character(s), truncated: %t", utf8.RuneCountInString(releaseNotes), truncated)
+	if build := newReleaseBuild(cfg); !isEmptyBuild(build) {
+		log.Printf("- Build: branch: %s, commit: %s", build.BranchName, build.CommitHash)
+	}
+	log.Printf("- Mandatory: %t, notify testers: %t", cfg.Mandatory, cfg.NotifyTesters)
+	log.Printf("- Fail mode: %s, distribution concurrency: %d", cfg.FailMode, cfg.DistributionConcurrency)
+	for _, planned := range plan.Groups {
+		log.Printf("- Group: %s (public: %t)", planned.Name, planned.Group.IsPublic)
+	}
+	for _, storeName := range plan.Stores {
+		log.Printf("- Store: %s", storeName)
+	}
+	for _, email := range plan.Testers {
+		log.Printf("- Tester: %s", email)
+	}
+	fmt.Println()
+
+	if len(problems) > 0 {
+		failf("Dry run found %d problem(s):\n- %s", len(problems), strings.Join(problems, "\n- "))
+	}
+
+	log.Infof("Exporting outputs")
+
+	outputs := map[string]string{
+		statusEnvKey:             dryRunStatus,
+		failedDestinationsEnvKey: "[]",
+		releaseNotesEnvKey:       releaseNotes,
+	}
+	setPublicInstallPageOutputs(outputs, urls, publicGroups)
+
+	exportOutputs(outputs)
+
+	log.Donef("- Done")
+	log.Warnf("Dry run: no release was created")
+}
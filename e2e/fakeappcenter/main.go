@@ -0,0 +1,156 @@
+// Command fakeappcenter serves the fake App Center API, so the step's e2e workflows can run without a real App Center account.
+//
+// Example:
+//
+//	go run ./e2e/fakeappcenter -addr 127.0.0.1:8765 -groups Collaborators -public-groups Public -fail "POST /testers$ 500 1"
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/fake"
+)
+
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+func main() {
+	var (
+		addr           = flag.String("addr", "127.0.0.1:8765", "address to listen on")
+		owner          = flag.String("owner", "fake-owner", "owner of the app")
+		appName        = flag.String("app", "fake-app", "name of the app")
+		groups         = flag.String("groups", "", "comma-separated private distribution groups")
+		publicGroups   = flag.String("public-groups", "", "comma-separated public distribution groups")
+		stores         = flag.String("stores", "", "comma-separated distribution stores")
+		uploadStatuses = flag.String("upload-statuses", "", "comma-separated upload statuses returned while polling the upload")
+		failures       stringList
+		delays         stringList
+		throttles      stringList
+	)
+	flag.Var(&failures, "fail", `scripted failure: "METHOD PATH_REGEXP STATUS TIMES", can be repeated`)
+	flag.Var(&delays, "delay", `scripted delay: "METHOD PATH_REGEXP DURATION", can be repeated`)
+	flag.Var(&throttles, "throttle", `scripted 429 response: "METHOD PATH_REGEXP RETRY_AFTER TIMES", can be repeated`)
+	flag.Parse()
+
+	server := fake.NewUnstartedServer()
+
+	app := server.AddApp(*owner, *appName)
+	for _, name := range splitList(*groups) {
+		app.AddGroup(name, false)
+	}
+	for _, name := range splitList(*publicGroups) {
+		app.AddGroup(name, true)
+	}
+	for _, name := range splitList(*stores) {
+		app.AddStore(name, "googleplay")
+	}
+	app.UploadStatuses = splitList(*uploadStatuses)
+
+	for _, failure := range failures {
+		fields := strings.Fields(failure)
+		if len(fields) != 4 {
+			failf("Invalid failure: %s", failure)
+		}
+
+		status, err := strconv.Atoi(fields[2])
+		if err != nil {
+			failf("Invalid failure status: %s", failure)
+		}
+		times, err := strconv.Atoi(fields[3])
+		if err != nil {
+			failf("Invalid failure times: %s", failure)
+		}
+
+		server.Fail(fields[0], fields[1], status, times)
+	}
+
+	for _, delay := range delays {
+		fields := strings.Fields(delay)
+		if len(fields) != 3 {
+			failf("Invalid delay: %s", delay)
+		}
+
+		duration, err := time.ParseDuration(fields[2])
+		if err != nil {
+			failf("Invalid delay duration: %s", delay)
+		}
+
+		server.Delay(fields[0], fields[1], duration)
+	}
+
+	for _, throttle := range throttles {
+		fields := strings.Fields(throttle)
+		if len(fields) != 4 {
+			failf("Invalid throttle: %s", throttle)
+		}
+
+		retryAfter, err := time.ParseDuration(fields[2])
+		if err != nil {
+			failf("Invalid throttle retry after: %s", throttle)
+		}
+		times, err := strconv.Atoi(fields[3])
+		if err != nil {
+			failf("Invalid throttle times: %s", throttle)
+		}
+
+		server.Throttle(fields[0], fields[1], retryAfter, times)
+	}
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		failf("Failed to listen on %s: %s", *addr, err)
+	}
+	if err := server.Listener.Close(); err != nil {
+		log.Warnf("Failed to close the default listener: %s", err)
+	}
+	server.Listener = listener
+	server.Start()
+
+	log.Infof("Fake App Center API listening on %s", server.URL)
+	log.Printf("- App: %s/%s", *owner, *appName)
+	log.Printf("- API token: %s", fake.Token)
+	fmt.Println()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	<-signals
+
+	for _, request := range server.Requests() {
+		log.Printf("%s %s", request.Method, request.Path)
+	}
+
+	server.Close()
+}
+
+func failf(format string, args ...interface{}) {
+	log.Errorf(format, args...)
+	os.Exit(1)
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/command"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/model"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/deployer"
+)
+
+const (
+	releaseNotesSourceText = "text"
+	releaseNotesSourceGit  = "git"
+
+	// gitReleaseNotesFallbackCommits is the number of commits listed if the previous release's commit is unknown.
+	gitReleaseNotesFallbackCommits = 20
+
+	gitLogFieldSeparator = "\x1f"
+	ticketURLPlaceholder = "{ticket}"
+)
+
+// generateGitReleaseNotes lists the commits since the commit of the app's (or the configured group's) latest release.
+// The group is looked up among the app's groups first, as the latest release of an unknown group is reported as no release.
+func generateGitReleaseNotes(cfg config, appAPI appcenter.AppAPI, groups []model.Group) (string, error) {
+	var previous model.Release
+	var err error
+	if cfg.ReleaseNotesGitGroup != "" {
+		group, lookupErr := deployer.LookupGroup(groups, cfg.ReleaseNotesGitGroup)
+		if lookupErr != nil {
+			return "", fmt.Errorf("release_notes_git_group (%s): %w", cfg.ReleaseNotesGitGroup, lookupErr)
+		}
+
+		previous, err = appAPI.LatestReleaseInGroup(group.Name)
+	} else {
+		previous, err = appAPI.LatestRelease()
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch the previous release: %w", err)
+	}
+
+	if previous.ID != 0 {
+		log.Printf("Previous release: %d (%s), commit: %s", previous.ID, previous.ShortVersion, previous.Build.CommitHash)
+	} else {
+		log.Printf("No previous release found")
+	}
+
+	generator, err := newGitReleaseNotesGenerator("", cfg.ReleaseNotesTicketPattern, cfg.ReleaseNotesTicketURL)
+	if err != nil {
+		return "", err
+	}
+
+	return generator.generate(previous.Build.CommitHash)
+}
+
+type gitCommit struct {
+	Hash    string
+	Parents []string
+	Subject string
+}
+
+func (c gitCommit) isMerge() bool {
+	return len(c.Parents) > 1
+}
+
+func (c gitCommit) shortHash() string {
+	if len(c.Hash) > 7 {
+		return c.Hash[:7]
+	}
+
+	return c.Hash
+}
+
+// gitReleaseNotesGenerator builds release notes from the local git history.
+type gitReleaseNotesGenerator struct {
+	dir           string
+	ticketPattern *regexp.Regexp
+	ticketURL     string
+}
+
+func newGitReleaseNotesGenerator(dir, ticketPattern, ticketURL string) (gitReleaseNotesGenerator, error) {
+	generator := gitReleaseNotesGenerator{
+		dir:       dir,
+		ticketURL: ticketURL,
+	}
+
+	if ticketPattern != "" {
+		pattern, err := regexp.Compile(ticketPattern)
+		if err != nil {
+			return gitReleaseNotesGenerator{}, fmt.Errorf("invalid ticket pattern: %s", err)
+		}
+
+		generator.ticketPattern = pattern
+	}
+
+	return generator, nil
+}
+
+// generate lists the commits between sinceCommit and HEAD, the commits brought in by a merge commit are grouped under it.
+// If sinceCommit is empty or not part of the local history, the latest commits are listed instead.
+func (g gitReleaseNotesGenerator) generate(sinceCommit string) (string, error) {
+	revisionRange := []string{"-n", fmt.Sprint(gitReleaseNotesFallbackCommits), "HEAD"}
+
+	switch {
+	case sinceCommit == "":
+		log.Warnf("The previous release has no commit hash, listing the last %d commit(s)", gitReleaseNotesFallbackCommits)
+	case !g.hasCommit(sinceCommit):
+		log.Warnf("Commit %s of the previous release is not part of the local history (is the repository a shallow clone?), listing the last %d commit(s)", sinceCommit, gitReleaseNotesFallbackCommits)
+	default:
+		revisionRange = []string{sinceCommit + "..HEAD"}
+	}
+
+	commits, err := g.log(append([]string{"--first-parent"}, revisionRange...)...)
+	if err != nil {
+		return "", err
+	}
+
+	var lines []string
+	for _, commit := range commits {
+		lines = append(lines, fmt.Sprintf("- %s (%s)", g.linkTickets(commit.Subject), commit.shortHash()))
+
+		if !commit.isMerge() {
+			continue
+		}
+
+		merged, err := g.log(fmt.Sprintf("%s..%s", commit.Parents[0], commit.Hash), "--no-merges")
+		if err != nil {
+			return "", err
+		}
+
+		for _, mergedCommit := range merged {
+			lines = append(lines, fmt.Sprintf("  - %s (%s)", g.linkTickets(mergedCommit.Subject), mergedCommit.shortHash()))
+		}
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+func (g gitReleaseNotesGenerator) hasCommit(hash string) bool {
+	cmd := command.New("git", "cat-file", "-e", hash+"^{commit}").SetDir(g.dir)
+	_, err := cmd.RunAndReturnTrimmedCombinedOutput()
+
+	return err == nil
+}
+
+func (g gitReleaseNotesGenerator) log(args ...string) ([]gitCommit, error) {
+	format := strings.Join([]string{"%H", "%P", "%s"}, gitLogFieldSeparator)
+	cmd := command.New("git", append([]string{"log", "--format=" + format}, args...)...).SetDir(g.dir)
+
+	out, err := cmd.RunAndReturnTrimmedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %s", cmd.PrintableCommandArgs(), err)
+	}
+
+	var commits []gitCommit
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.SplitN(line, gitLogFieldSeparator, 3)
+		if len(fields) != 3 {
+			continue
+		}
+
+		commits = append(commits, gitCommit{
+			Hash:    fields[0],
+			Parents: strings.Fields(fields[1]),
+			Subject: fields[2],
+		})
+	}
+
+	return commits, nil
+}
+
+// linkTickets turns the ticket keys of a commit subject into Markdown links.
+func (g gitReleaseNotesGenerator) linkTickets(subject string) string {
+	if g.ticketPattern == nil || g.ticketURL == "" {
+		return subject
+	}
+
+	return g.ticketPattern.ReplaceAllStringFunc(subject, func(key string) string {
+		return fmt.Sprintf("[%s](%s)", key, strings.ReplaceAll(g.ticketURL, ticketURLPlaceholder, key))
+	})
+}
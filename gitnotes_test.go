@@ -0,0 +1,257 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// gitTestRepo is a temporary git repository.
+type gitTestRepo struct {
+	t   *testing.T
+	dir string
+}
+
+func newGitTestRepo(t *testing.T) gitTestRepo {
+	repo := gitTestRepo{t: t, dir: t.TempDir()}
+	repo.git("init", "-q", "-b", "main")
+
+	return repo
+}
+
+// git runs a git command in the repository and returns its trimmed output.
+func (r gitTestRepo) git(args ...string) string {
+	r.t.Helper()
+
+	cmd := exec.Command("git", append([]string{"-c", "user.name=Test", "-c", "user.email=test@example.com", "-c", "commit.gpgsign=false"}, args...)...)
+	cmd.Dir = r.dir
+	cmd.Env = append(os.Environ(), "GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1")
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		r.t.Fatalf("git %s failed: %s\n%s", strings.Join(args, " "), err, out)
+	}
+
+	return strings.TrimSpace(string(out))
+}
+
+// commit commits a change with the subject and returns the short hash of the commit.
+func (r gitTestRepo) commit(subject string) string {
+	r.t.Helper()
+
+	// Every commit adds a new file, so branches merge without conflicts.
+	f, err := os.CreateTemp(r.dir, "change-*.txt")
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	if _, err := f.WriteString(subject + "\n"); err != nil {
+		r.t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		r.t.Fatal(err)
+	}
+
+	r.git("add", "-A")
+	r.git("commit", "-q", "-m", subject)
+
+	return r.git("rev-parse", "--short=7", "HEAD")
+}
+
+func Test_gitReleaseNotesGenerator_generate(t *testing.T) {
+	repo := newGitTestRepo(t)
+	initial := repo.commit("Initial commit")
+	previousRelease := repo.git("rev-parse", "HEAD")
+	fix := repo.commit("ABC-1 Fix the crash on start")
+
+	repo.git("checkout", "-q", "-b", "feature/login")
+	login := repo.commit("ABC-2 Add login")
+	polish := repo.commit("Polish the login screen")
+	repo.git("checkout", "-q", "main")
+	repo.git("merge", "-q", "--no-ff", "-m", "Merge branch 'feature/login'", "feature/login")
+	merge := repo.git("rev-parse", "--short=7", "HEAD")
+	bump := repo.commit("Bump version to 1.1")
+
+	sinceRelease := strings.Join([]string{
+		"- Bump version to 1.1 (" + bump + ")",
+		"- Merge branch 'feature/login' (" + merge + ")",
+		"  - Polish the login screen (" + polish + ")",
+		"  - [ABC-2](https://jira.example.com/browse/ABC-2) Add login (" + login + ")",
+		"- [ABC-1](https://jira.example.com/browse/ABC-1) Fix the crash on start (" + fix + ")",
+	}, "\n")
+	lastCommits := sinceRelease + "\n- Initial commit (" + initial + ")"
+
+	tests := []struct {
+		name        string
+		sinceCommit string
+		want        string
+	}{
+		{
+			name:        "commits since the previous release, grouped by merge",
+			sinceCommit: previousRelease,
+			want:        sinceRelease,
+		},
+		{
+			name:        "short hash of the previous release",
+			sinceCommit: initial,
+			want:        sinceRelease,
+		},
+		{
+			name:        "no commit of the previous release falls back to the last commits",
+			sinceCommit: "",
+			want:        lastCommits,
+		},
+		{
+			name:        "commit missing from the local history falls back to the last commits",
+			sinceCommit: "0123456789abcdef0123456789abcdef01234567",
+			want:        lastCommits,
+		},
+		{
+			name:        "no commits since the previous release",
+			sinceCommit: repo.git("rev-parse", "HEAD"),
+			want:        "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			generator, err := newGitReleaseNotesGenerator(repo.dir, `[A-Z]+-\d+`, "https://jira.example.com/browse/{ticket}")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := generator.generate(tt.sinceCommit)
+			if err != nil {
+				t.Fatalf("generate() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("generate() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_gitReleaseNotesGenerator_generate_fallbackLimit(t *testing.T) {
+	repo := newGitTestRepo(t)
+	for i := 0; i < gitReleaseNotesFallbackCommits+5; i++ {
+		repo.commit("Commit")
+	}
+
+	generator, err := newGitReleaseNotesGenerator(repo.dir, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := generator.generate("")
+	if err != nil {
+		t.Fatalf("generate() error = %v", err)
+	}
+	if lines := strings.Split(got, "\n"); len(lines) != gitReleaseNotesFallbackCommits {
+		t.Errorf("generate() = %d line(s), want %d", len(lines), gitReleaseNotesFallbackCommits)
+	}
+}
+
+func Test_gitReleaseNotesGenerator_generate_notARepository(t *testing.T) {
+	generator, err := newGitReleaseNotesGenerator(t.TempDir(), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := generator.generate(""); err == nil {
+		t.Errorf("generate() error = nil, want an error outside of a git repository")
+	}
+}
+
+func Test_gitReleaseNotesGenerator_log(t *testing.T) {
+	repo := newGitTestRepo(t)
+	repo.commit("Initial commit")
+	repo.git("checkout", "-q", "-b", "feature")
+	repo.commit("Subject with | pipes, \"quotes\" and a trailing space ")
+	repo.git("checkout", "-q", "main")
+	repo.commit("Second commit")
+	repo.git("merge", "-q", "--no-ff", "-m", "Merge feature", "feature")
+
+	generator, err := newGitReleaseNotesGenerator(repo.dir, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commits, err := generator.log("HEAD")
+	if err != nil {
+		t.Fatalf("log() error = %v", err)
+	}
+
+	var subjects []string
+	for _, commit := range commits {
+		subjects = append(subjects, commit.Subject)
+		if len(commit.Hash) != 40 {
+			t.Errorf("commit %q hash = %q, want a full hash", commit.Subject, commit.Hash)
+		}
+	}
+	want := []string{"Merge feature", "Second commit", "Subject with | pipes, \"quotes\" and a trailing space", "Initial commit"}
+	if !reflect.DeepEqual(subjects, want) {
+		t.Errorf("log() subjects = %q, want %q", subjects, want)
+	}
+
+	if !commits[0].isMerge() || len(commits[0].Parents) != 2 {
+		t.Errorf("log() merge commit parents = %v, want 2", commits[0].Parents)
+	}
+	if commits[1].isMerge() || len(commits[3].Parents) != 0 {
+		t.Errorf("log() parents = %v and %v, want 1 and 0", commits[1].Parents, commits[3].Parents)
+	}
+}
+
+func Test_gitReleaseNotesGenerator_linkTickets(t *testing.T) {
+	tests := []struct {
+		name          string
+		ticketPattern string
+		ticketURL     string
+		subject       string
+		want          string
+	}{
+		{
+			name:          "ticket keys are linked",
+			ticketPattern: `[A-Z]+-\d+`,
+			ticketURL:     "https://jira.example.com/browse/{ticket}",
+			subject:       "ABC-1, DEF-22: Fix the crash",
+			want:          "[ABC-1](https://jira.example.com/browse/ABC-1), [DEF-22](https://jira.example.com/browse/DEF-22): Fix the crash",
+		},
+		{
+			name:          "URL without placeholder",
+			ticketPattern: `#\d+`,
+			ticketURL:     "https://example.com/issues",
+			subject:       "Fix #12",
+			want:          "Fix [#12](https://example.com/issues)",
+		},
+		{
+			name:          "no ticket URL",
+			ticketPattern: `[A-Z]+-\d+`,
+			subject:       "ABC-1 Fix the crash",
+			want:          "ABC-1 Fix the crash",
+		},
+		{
+			name:      "no ticket pattern",
+			ticketURL: "https://jira.example.com/browse/{ticket}",
+			subject:   "ABC-1 Fix the crash",
+			want:      "ABC-1 Fix the crash",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			generator, err := newGitReleaseNotesGenerator("", tt.ticketPattern, tt.ticketURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := generator.linkTickets(tt.subject); got != tt.want {
+				t.Errorf("linkTickets() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_newGitReleaseNotesGenerator_invalidTicketPattern(t *testing.T) {
+	if _, err := newGitReleaseNotesGenerator("", "[A-Z", ""); err == nil {
+		t.Errorf("newGitReleaseNotesGenerator() error = nil, want an error for an invalid pattern")
+	}
+}
@@ -3,13 +3,11 @@ module github.com/bitrise-steplib/steps-appcenter-deploy-android
 go 1.18
 
 require (
-	github.com/bitrise-io/appcenter v1.0.1
 	github.com/bitrise-io/go-steputils v1.0.5
 	github.com/bitrise-io/go-utils v1.0.9
+	github.com/hashicorp/go-retryablehttp v0.7.7
+	github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e
+	golang.org/x/sync v0.3.0
 )
 
-require (
-	github.com/hashicorp/go-cleanhttp v0.5.2 // indirect
-	github.com/hashicorp/go-retryablehttp v0.7.7 // indirect
-	golang.org/x/sync v0.3.0 // indirect
-)
+require github.com/hashicorp/go-cleanhttp v0.5.2 // indirect
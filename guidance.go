@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/client"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/model"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/deployer"
+)
+
+// errorGuide turns App Center API errors into advice on how to fix them.
+// Its app and groups are filled in by main once they are known.
+type errorGuide struct {
+	app    model.App
+	groups []model.Group
+}
+
+var guide errorGuide
+
+// advise returns advice on how to fix the first App Center API error among args, or empty if there is none.
+func (g errorGuide) advise(args ...interface{}) string {
+	for _, arg := range args {
+		err, ok := arg.(error)
+		if !ok {
+			continue
+		}
+
+		var notFound *deployer.GroupNotFoundError
+		if errors.As(err, &notFound) {
+			return fmt.Sprintf("Distribution group (%s) does not exist in app (%s/%s). %s", notFound.Name, g.app.Owner, g.app.AppName, availableGroups(notFound.Available))
+		}
+
+		apiErr, ok := client.AsAPIError(err)
+		if !ok {
+			continue
+		}
+
+		return g.adviseAPIError(apiErr)
+	}
+
+	return ""
+}
+
+func (g errorGuide) adviseAPIError(apiErr *client.APIError) string {
+	owner, appName := g.app.Owner, g.app.AppName
+
+	switch apiErr.StatusCode {
+	case http.StatusUnauthorized:
+		return "The API token is invalid or expired. Create a new token under App Center > Account settings > User API tokens, and update the api_token input."
+	case http.StatusForbidden:
+		return fmt.Sprintf("The API token has no access to app (%s/%s). Make sure the token's user is a collaborator of the app with at least Developer role, and the token has Full Access.", owner, appName)
+	case http.StatusNotFound:
+		if name, ok := pathSegmentAfter(apiErr.Path, "distribution_groups"); ok {
+			return fmt.Sprintf("Distribution group (%s) does not exist in app (%s/%s). %s", name, owner, appName, availableGroups(g.groupNames()))
+		}
+		if name, ok := pathSegmentAfter(apiErr.Path, "distribution_stores"); ok {
+			return fmt.Sprintf("Distribution store (%s) does not exist in app (%s/%s), check the distribution_store input.", name, owner, appName)
+		}
+		if strings.HasSuffix(apiErr.Path, fmt.Sprintf("/v0.1/apps/%s/%s", owner, appName)) {
+			return fmt.Sprintf("App (%s/%s) does not exist. Use the owner and app name from the app's App Center URL, not its display name.", owner, appName)
+		}
+	}
+
+	return ""
+}
+
+// pathSegmentAfter returns the path segment following the given one, the path is decoded like url.URL.Path.
+func pathSegmentAfter(path, segment string) (string, bool) {
+	segments := strings.Split(path, "/")
+	for idx := 0; idx < len(segments)-1; idx++ {
+		if segments[idx] == segment {
+			return segments[idx+1], true
+		}
+	}
+
+	return "", false
+}
+
+func (g errorGuide) groupNames() []string {
+	var names []string
+	for _, group := range g.groups {
+		names = append(names, group.DisplayName)
+	}
+
+	return names
+}
+
+func availableGroups(names []string) string {
+	if len(names) == 0 {
+		return "The app has no distribution groups."
+	}
+
+	return fmt.Sprintf("Available groups: %s.", strings.Join(names, ", "))
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/client"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/model"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/deployer"
+)
+
+func Test_errorGuide_advise(t *testing.T) {
+	g := errorGuide{
+		app:    model.App{Owner: "owner", AppName: "app"},
+		groups: []model.Group{{Name: "collaborators", DisplayName: "Collaborators"}, {Name: "beta", DisplayName: "Beta"}},
+	}
+
+	tests := []struct {
+		name string
+		args []interface{}
+		want string
+	}{
+		{
+			name: "unknown configured group lists the available groups",
+			args: []interface{}{"Nope", fmt.Errorf("failed to add group(Nope) to the release, error: %w", &deployer.GroupNotFoundError{Name: "Nope", Available: []string{"Collaborators", "Beta"}})},
+			want: "Distribution group (Nope) does not exist in app (owner/app). Available groups: Collaborators, Beta.",
+		},
+		{
+			name: "404 of a group endpoint lists the app's groups",
+			args: []interface{}{&client.APIError{StatusCode: 404, Path: "/v0.1/apps/owner/app/distribution_groups/Nope Group"}},
+			want: "Distribution group (Nope Group) does not exist in app (owner/app). Available groups: Collaborators, Beta.",
+		},
+		{
+			name: "404 of a group endpoint keeps a percent sign in the group name",
+			args: []interface{}{&client.APIError{StatusCode: 404, Path: "/v0.1/apps/owner/app/distribution_groups/100% Testers/members"}},
+			want: "Distribution group (100% Testers) does not exist in app (owner/app).",
+		},
+		{
+			name: "invalid token",
+			args: []interface{}{fmt.Errorf("failed: %w", &client.APIError{StatusCode: 401})},
+			want: "The API token is invalid or expired.",
+		},
+		{
+			name: "no error among the args",
+			args: []interface{}{"text", 1},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := g.advise(tt.args...)
+			if tt.want == "" && got != "" || !strings.HasPrefix(got, tt.want) {
+				t.Errorf("advise() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
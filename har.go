@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"path/filepath"
+
+	"github.com/bitrise-io/go-steputils/tools"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/client"
+)
+
+const (
+	harFileName   = "appcenter-deploy.har"
+	harPathEnvKey = "APPCENTER_DEPLOY_HAR_PATH"
+)
+
+// harRecording records the App Center traffic of a debug run, it is nil when debug logs are disabled.
+var harRecording *trafficRecording
+
+type trafficRecording struct {
+	recorder *client.HARRecorder
+	path     string
+}
+
+// recordTraffic wraps the transport with a HAR recorder written to the deploy directory if debug is enabled.
+func recordTraffic(cfg config, transport http.RoundTripper) http.RoundTripper {
+	if !cfg.Debug {
+		return transport
+	}
+
+	harRecording = &trafficRecording{
+		recorder: client.NewHARRecorder(transport),
+		path:     filepath.Join(cfg.DeployDir, harFileName),
+	}
+
+	return harRecording.recorder
+}
+
+// write writes the recorded traffic and returns its path, or an empty path if nothing is recorded.
+func (r *trafficRecording) write() (string, error) {
+	if r == nil {
+		return "", nil
+	}
+
+	if err := r.recorder.Write(r.path); err != nil {
+		return "", err
+	}
+
+	return r.path, nil
+}
+
+// exportHARPath writes the recorded traffic of a failed run and exports its path.
+func exportHARPath() {
+	harPath, err := harRecording.write()
+	if err != nil {
+		log.Warnf("Failed to write HTTP recording, error: %s", err)
+		return
+	}
+	if harPath == "" {
+		return
+	}
+
+	if err := tools.ExportEnvironmentWithEnvman(harPathEnvKey, harPath); err != nil {
+		log.Errorf("Failed to export environment variable: %s with value: %s. Error: %s", harPathEnvKey, harPath, err)
+	}
+}
@@ -3,48 +3,130 @@ package main
 import (
 	"fmt"
 	"os"
+	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
-	"github.com/bitrise-io/appcenter"
-	"github.com/bitrise-io/appcenter/client"
-	"github.com/bitrise-io/appcenter/model"
 	"github.com/bitrise-io/go-steputils/stepconf"
 	"github.com/bitrise-io/go-steputils/tools"
 	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/client"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/model"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/deployer"
 )
 
-const statusEnvKey = "APPCENTER_DEPLOY_STATUS"
+const (
+	statusEnvKey             = "APPCENTER_DEPLOY_STATUS"
+	failedDestinationsEnvKey = "APPCENTER_DEPLOY_FAILED_DESTINATIONS"
+	releaseNotesEnvKey       = "APPCENTER_DEPLOY_RELEASE_NOTES"
+)
 
 type config struct {
 	Debug              bool            `env:"debug,required"`
-	AppPath            string          `env:"app_path,file"`
+	AppPath            string          `env:"app_path"`
 	AppName            string          `env:"app_name,required"`
 	APIToken           stepconf.Secret `env:"api_token,required"`
 	OwnerName          string          `env:"owner_name,required"`
 	Mandatory          bool            `env:"mandatory,required"`
 	MappingPath        string          `env:"mapping_path"`
 	ReleaseNotes       string          `env:"release_notes"`
+	ReleaseNotesFile   string          `env:"release_notes_file"`
 	NotifyTesters      bool            `env:"notify_testers,required"`
 	DistributeAllGroup bool            `env:"all_distribution_groups"`
 	DistributionGroup  string          `env:"distribution_group"`
 	DistributionStore  string          `env:"distribution_store"`
 	DistributionTester string          `env:"distribution_tester"`
+	FailMode           string          `env:"fail_mode,opt[fail_fast,continue]" required:"true"`
+	FailOnPartial      bool            `env:"fail_on_partial_distribution"`
+
+	DistributionConcurrency int    `env:"distribution_concurrency,range[1..50]" required:"true"`
+	DryRun                  bool   `env:"dry_run"`
+	DeployDir               string `env:"deploy_dir"`
+
+	ReleaseNotesTemplate      bool   `env:"release_notes_template"`
+	ReleaseNotesSource        string `env:"release_notes_source,opt[text,git]" required:"true"`
+	ReleaseNotesGitGroup      string `env:"release_notes_git_group"`
+	ReleaseNotesTicketPattern string `env:"release_notes_ticket_pattern"`
+	ReleaseNotesTicketURL     string `env:"release_notes_ticket_url"`
+
+	BuildBranch        string `env:"build_branch"`
+	BuildCommitHash    string `env:"build_commit_hash"`
+	BuildCommitMessage string `env:"build_commit_message"`
+
+	Mode                 string `env:"mode,opt[deploy,promote,rollback,cleanup]" required:"true"`
+	ReleaseID            int    `env:"release_id"`
+	PromoteFromGroup     string `env:"promote_from_group"`
+	RollbackRedistribute bool   `env:"rollback_redistribute"`
+
+	CleanupAction        string `env:"cleanup_action,opt[disable,delete]" required:"true"`
+	CleanupOlderThanDays int    `env:"cleanup_older_than_days"`
+	CleanupKeepLatest    int    `env:"cleanup_keep_latest"`
+	CleanupBranchPattern string `env:"cleanup_branch_pattern"`
+	CleanupGroup         string `env:"cleanup_group"`
+
+	WebhookURL         stepconf.Secret `env:"webhook_url"`
+	WebhookTemplate    string          `env:"webhook_template"`
+	WebhookOn          string          `env:"webhook_on,opt[success,failure,always]"`
+	WebhookFailOnError bool            `env:"webhook_fail_on_error"`
+
+	APIBaseURL     string `env:"api_base_url,required"`
+	PortalBaseURL  string `env:"portal_base_url,required"`
+	InstallBaseURL string `env:"install_base_url,required"`
+
+	ProxyURL       stepconf.Secret `env:"proxy_url"`
+	CACertPath     string          `env:"ca_cert_path"`
+	ClientCertPath string          `env:"client_cert_path"`
+	ClientKeyPath  string          `env:"client_key_path"`
+
+	APIMaxRetries    int `env:"api_max_retries,range[0..20]" required:"true"`
+	APIMinBackoff    int `env:"api_min_backoff,range[0..600]" required:"true"`
+	APIMaxBackoff    int `env:"api_max_backoff,range[0..600]" required:"true"`
+	APITimeout       int `env:"api_timeout,range[0..3600]" required:"true"`
+	UploadMaxRetries int `env:"upload_max_retries,range[0..20]" required:"true"`
+	UploadMinBackoff int `env:"upload_min_backoff,range[0..600]" required:"true"`
+	UploadMaxBackoff int `env:"upload_max_backoff,range[0..600]" required:"true"`
+	UploadTimeout    int `env:"upload_timeout,range[0..3600]" required:"true"`
+
+	UploadStatusMaxAttempts int `env:"upload_status_max_attempts,range[1..1000]" required:"true"`
+
+	CompareGroup           string  `env:"compare_group"`
+	MaxSizeIncreasePercent float64 `env:"max_size_increase_percent"`
+	MaxSizeIncreaseBytes   int     `env:"max_size_increase_bytes"`
+	AllowMinAPIChange      bool    `env:"allow_min_api_change"`
 }
 
 func main() {
 	var cfg config
+	if err := checkRequiredInputs(cfg, os.Getenv); err != nil {
+		failf("Issue with input: %s", err)
+	}
 	if err := stepconf.Parse(&cfg); err != nil {
 		failf("Issue with input: %s", err)
 	}
 	stepconf.Print(cfg)
 	fmt.Println()
 
+	// The webhook and proxy URLs often embed credentials, mask them like the API token in every log and error.
+	client.AddSecret(string(cfg.APIToken))
+	client.AddSecret(string(cfg.WebhookURL))
+	client.AddSecret(string(cfg.ProxyURL))
+
+	report = newDeployReport(cfg)
+
+	notifier, err := newWebhookNotifier(cfg)
+	if err != nil {
+		failf("Issue with input: %s", err)
+	}
+	webhook = notifier
+
 	app := model.App{
 		Owner:   cfg.OwnerName,
 		AppName: cfg.AppName,
 		AppType: model.AppTypeAndroid,
 	}
+	guide.app = app
 
 	releaseOptions := model.ReleaseOptions{
 		GroupNames:    strings.Split(cfg.DistributionGroup, "\n"),
@@ -54,189 +136,351 @@ func main() {
 		App:           app,
 	}
 
-	api := client.CreateAPIWithClientParams(string(cfg.APIToken))
+	base, err := newBaseURLs(cfg.PortalBaseURL, cfg.InstallBaseURL)
+	if err != nil {
+		failf("Issue with input: %s", err)
+	}
+
+	transportCfg := client.TransportConfig{
+		ProxyURL:       string(cfg.ProxyURL),
+		CACertPath:     cfg.CACertPath,
+		ClientCertPath: cfg.ClientCertPath,
+		ClientKeyPath:  cfg.ClientKeyPath,
+	}
+	transport, err := client.NewTransport(transportCfg)
+	if err != nil {
+		failf("Issue with input: %s", err)
+	}
+
+	apiRetryPolicy, uploadRetryPolicy, err := newRetryPolicies(cfg)
+	if err != nil {
+		failf("Issue with input: %s", err)
+	}
+
+	api, err := client.CreateAPIWithClientParams(string(cfg.APIToken),
+		client.WithBaseURL(cfg.APIBaseURL),
+		client.WithTransport(recordTraffic(cfg, transport)),
+		client.WithRetryPolicies(apiRetryPolicy, uploadRetryPolicy),
+		client.WithUploadStatusMaxAttempts(cfg.UploadStatusMaxAttempts),
+	)
+	if err != nil {
+		failf("Issue with input: %s", err)
+	}
 	appAPI := appcenter.CreateApplicationAPI(api, releaseOptions)
 
 	log.SetEnableDebugLog(cfg.Debug)
+	client.PrintTransport(transportCfg, transport, cfg.APIBaseURL)
+	log.Printf("API retry policy: %s", apiRetryPolicy)
+	log.Printf("Upload retry policy: %s", uploadRetryPolicy)
+	log.Printf("Upload status checks: at most %d", cfg.UploadStatusMaxAttempts)
+	fmt.Println()
 
-	log.Infof("Uploading binary")
+	log.Infof("Fetching app details")
+	phaseDone := report.Phase("app details")
 
-	release, err := appAPI.NewRelease()
+	appDetails, err := appAPI.Details()
 	if err != nil {
-		failf("Failed to create new release, error: %s", err)
+		failf("Failed to fetch app (%s/%s), error: %s", cfg.OwnerName, cfg.AppName, err)
 	}
 
+	log.Debugf("%+v", appDetails)
+
+	urls := newAppURLs(base, appDetails, app)
+	phaseDone()
+
 	log.Donef("- Done")
 	fmt.Println()
 
-	releaseAPI := appcenter.CreateReleaseAPI(api, release, releaseOptions)
-
-	// We think there is a limitation in the App Center API where release notes can only be modified
-	// after a release has been created (using separate endpoints). This leads to a race condition
-	// where the generated email for the release does not necessarily contain the release notes
-	// because it was added later via a separate API call. Anecdotally, this timing issue can be somewhat mitigated
-	// by adding the release notes as soon as possible after the release was created (i.e. calling the endpoints right after each other).
-	// In the future, we should investigate if there is a solution for updating the release notes reliably,
-	// or switch entirely to the App Center CLI which might be able to handle this correctly.
-	if len(cfg.ReleaseNotes) > 0 {
-		log.Infof("Setting release notes")
-		if err := releaseAPI.SetReleaseNote(cfg.ReleaseNotes); err != nil {
-			failf("Failed to set release note, error: %s", err)
-		}
-		log.Donef("- Done")
-		fmt.Println()
-	}
-
-	log.Infof("Setting distribution group(s)")
+	log.Infof("Fetching distribution group(s)")
+	phaseDone = report.Phase("distribution groups")
 
-	err = releaseAPI.AddGroupsToRelease(releaseOptions.GroupNames)
+	groups, err := appAPI.AllGroups()
 	if err != nil {
-		failf("Failed to set groups on the release %s, groups: %s, error: %s", release.ID, releaseOptions.GroupNames, err)
+		failf("Failed to fetch groups, error: %s", err)
 	}
+	guide.groups = groups
+	phaseDone()
 
-	if len(cfg.MappingPath) > 0 {
-		log.Infof("Uploading mapping file")
-		if err := releaseAPI.UploadSymbol(cfg.MappingPath); err != nil {
-			failf("Failed to upload symbol file(%s), error: %s", cfg.MappingPath, err)
-		}
-		log.Donef("- Done")
-		fmt.Println()
-	}
+	log.Donef("- Done")
+	fmt.Println()
 
-	log.Infof("Gatehering public group(s)")
+	plan := deployer.NewPlan(newDeployerConfig(cfg), groups)
+
+	switch cfg.Mode {
+	case modePromote:
+		promote(cfg, api, appAPI, releaseOptions, urls, groups, plan)
+		return
+	case modeRollback:
+		rollback(cfg, api, appAPI, releaseOptions, plan)
+		return
+	case modeCleanup:
+		cleanup(cfg, api, appAPI, releaseOptions, groups)
+		return
+	}
 
-	var publicGroup []string
-	if cfg.DistributeAllGroup {
-		log.Infof("Gatehering all public group(s)")
+	var notes releaseNotesInput
+	if cfg.ReleaseNotesSource == releaseNotesSourceGit {
+		log.Infof("Generating release notes from git history")
+		phaseDone = report.Phase("git release notes")
 
-		groups, err := appAPI.AllGroups()
+		generated, err := generateGitReleaseNotes(cfg, appAPI, groups)
 		if err != nil {
-			failf("Failed to fetch groups, error: %s", err)
+			failf("Failed to generate release notes, error: %s", err)
 		}
 
-		for _, group := range groups {
-			if err := releaseAPI.AddGroup(group); err != nil {
-				failf("Failed to add group(%s) to the release, error: %s", group.DisplayName, err)
-			}
+		log.Printf("%s", generated)
+		notes = releaseNotesInput{text: generated}
+		phaseDone()
 
-			if group.IsPublic {
-				publicGroup = append(publicGroup, group.DisplayName)
-			}
-		}
+		log.Donef("- Done")
+		fmt.Println()
 	} else {
-		log.Infof("Gatehering config public group(s)")
-
-		err = releaseAPI.AddGroupsToRelease(releaseOptions.GroupNames)
+		text, err := loadReleaseNotes(cfg.ReleaseNotes, cfg.ReleaseNotesFile)
 		if err != nil {
-			failf("Failed to set groups on the release %s, groups: %s, error: %s", release.ID, releaseOptions.GroupNames, err)
+			failf("Failed to load release notes, error: %s", err)
 		}
 
-		for _, groupName := range releaseOptions.GroupNames {
-			groupName = strings.TrimSpace(groupName)
-			if len(groupName) == 0 {
-				continue
+		notes = releaseNotesInput{text: text}
+		if cfg.ReleaseNotesTemplate {
+			if _, err := parseReleaseNotes(text); err != nil {
+				failf("Failed to load release notes, error: %s", err)
 			}
 
-			log.Printf("- %s", groupName)
+			notes.isTemplate = true
+		}
+	}
 
-			group, err := appAPI.Groups(groupName)
-			if err != nil {
-				failf("Failed to fetch group with name: (%s), error: %s", groupName, err)
-			}
+	if cfg.DryRun {
+		dryRun(cfg, appAPI, urls, groups, plan, notes)
+		return
+	}
 
-			log.Debugf("%+v", group)
+	// The dry run lists every problem of the inputs, the artifacts are only hashed for the report of a real deploy.
+	if err := report.addArtifact(cfg.AppPath); err != nil {
+		failf("Issue with input: app_path: %s", err)
+	}
 
-			if group.IsPublic {
-				publicGroup = append(publicGroup, groupName)
-			}
+	if len(cfg.MappingPath) > 0 {
+		if err := report.addArtifact(cfg.MappingPath); err != nil {
+			failf("Issue with input: mapping_path: %s", err)
 		}
 	}
 
+	compareGroup, err := comparedGroup(cfg, groups, plan)
+	if err != nil {
+		failf("Issue with input: %s", err)
+	}
+
+	log.Infof("Fetching the previous release")
+	phaseDone = report.Phase("previous release")
+
+	previousRelease, err := fetchPreviousRelease(appAPI, compareGroup)
+	if err != nil {
+		failf("Failed to fetch the previous release, error: %s", err)
+	}
+	phaseDone()
+
+	if previousRelease.ID != 0 {
+		log.Printf("- %d (%s (%s))", previousRelease.ID, previousRelease.ShortVersion, previousRelease.Version)
+	} else {
+		log.Printf("- No previous release")
+	}
+
 	log.Donef("- Done")
 	fmt.Println()
 
-	log.Infof("Setting distribution store(s)")
+	deploy(cfg, api, releaseOptions, groups, urls, notes, previousRelease, currentRunReporting())
+}
 
-	for _, storeName := range strings.Split(cfg.DistributionStore, "\n") {
-		storeName = strings.TrimSpace(storeName)
+// exportReleaseOutputs exports the outputs of a distributed release.
+func exportReleaseOutputs(cfg config, reporting runReporting, result deployer.Result, urls appURLs, releaseNotes string) error {
+	release, summary := result.Release, result.Summary
 
-		if len(storeName) == 0 {
-			continue
-		}
+	failedDestinationsJSON, err := summary.FailedJSON()
+	if err != nil {
+		return fmt.Errorf("failed to serialize failed destinations: %s", err)
+	}
 
-		log.Printf("- %s", storeName)
+	var outputs = map[string]string{
+		statusEnvKey:                    result.Status,
+		failedDestinationsEnvKey:        failedDestinationsJSON,
+		"APPCENTER_DEPLOY_INSTALL_URL":  release.InstallURL,
+		"APPCENTER_DEPLOY_DOWNLOAD_URL": release.DownloadURL,
+		"APPCENTER_RELEASE_PAGE_URL":    urls.releasePage(release.ID),
+		"APPCENTER_DEPLOY_RELEASE_ID":   strconv.Itoa(release.ID),
+		releaseNotesEnvKey:              releaseNotes,
+
+		"APPCENTER_DEPLOY_VERSION":               release.Version,
+		"APPCENTER_DEPLOY_SHORT_VERSION":         release.ShortVersion,
+		"APPCENTER_DEPLOY_BUNDLE_IDENTIFIER":     release.BundleIdentifier,
+		"APPCENTER_DEPLOY_SIZE":                  strconv.Itoa(release.Size),
+		"APPCENTER_DEPLOY_ANDROID_MIN_API_LEVEL": release.AndroidMinAPILevel,
+		"APPCENTER_DEPLOY_FINGERPRINT":           release.Fingerprint,
+		"APPCENTER_DEPLOY_UPLOADED_AT":           release.UploadedAt,
+		"APPCENTER_DEPLOY_APP_ICON_URL":          release.AppIconURL,
+		"APPCENTER_DEPLOY_DESTINATIONS":          strings.Join(summary.SucceededNames(), ","),
+	}
 
-		store, err := appAPI.Stores(storeName)
-		if err != nil {
-			failf("Failed to fetch store with name: (%s), error: %s", storeName, err)
-		}
+	setPublicInstallPageOutputs(outputs, urls, summary.PublicGroups)
+	setQRCodeOutputs(outputs, cfg.DeployDir, release, urls, summary.PublicGroups)
 
-		if err := releaseAPI.AddStore(store); err != nil {
-			failf("Failed to add store(%s) to the release, error: %s", storeName, err)
-		}
-	}
+	log.Infof("Exporting outputs")
+
+	reporting.exportOutputs(outputs)
 
 	log.Donef("- Done")
-	fmt.Println()
 
-	log.Infof("Setting distribution tester(s)")
+	return nil
+}
 
-	for _, email := range strings.Split(cfg.DistributionTester, "\n") {
-		email = strings.TrimSpace(email)
+// setPublicInstallPageOutputs fills the public install page outputs for the given public groups.
+func setPublicInstallPageOutputs(outputs map[string]string, urls appURLs, publicGroups []model.Group) {
+	var groupUrls []string
+	for _, group := range publicGroups {
+		groupUrls = append(groupUrls, urls.publicInstallPage(group))
+	}
+
+	if len(groupUrls) > 0 {
+		outputs["APPCENTER_PUBLIC_INSTALL_PAGE_URL"] = groupUrls[0]
+		outputs["APPCENTER_PUBLIC_INSTALL_PAGE_URLS"] = strings.Join(groupUrls, ", ")
+	} else {
+		outputs["APPCENTER_PUBLIC_INSTALL_PAGE_URL"] = ""
+		outputs["APPCENTER_PUBLIC_INSTALL_PAGE_URLS"] = ""
+	}
+}
 
-		if len(email) == 0 {
+// newRetryPolicies returns the retry policy of the App Center API calls and of the chunk and symbol uploads.
+// checkRequiredInputs returns an error listing the inputs tagged with required:"true" that are not set.
+// stepconf allows a single constraint per input, so required inputs with an opt or range constraint are tagged separately.
+func checkRequiredInputs(conf interface{}, getenv func(string) string) error {
+	var missing []string
+
+	t := reflect.TypeOf(conf)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Tag.Get("required") != "true" {
 			continue
 		}
 
-		log.Printf("- %s", email)
-
-		if err := releaseAPI.AddTester(email); err != nil {
-			failf("Failed to add tester(%s) to the release, error: %s", email, err)
+		key := strings.SplitN(field.Tag.Get("env"), ",", 2)[0]
+		if getenv(key) == "" {
+			missing = append(missing, key)
 		}
 	}
 
-	log.Donef("- Done")
-	fmt.Println()
+	if len(missing) > 0 {
+		return fmt.Errorf("required input(s) not set: %s", strings.Join(missing, ", "))
+	}
 
-	log.Infof("Exporting outputs")
+	return nil
+}
 
-	var groupUrls []string
-	for _, groupName := range publicGroup {
-		groupUrls = append(groupUrls, fmt.Sprintf("https://install.appcenter.ms/users/%s/apps/%s/distribution_groups/%s", cfg.OwnerName, cfg.AppName, groupName))
+func newRetryPolicies(cfg config) (client.RetryPolicy, client.RetryPolicy, error) {
+	apiPolicy := client.RetryPolicy{
+		MaxRetries: cfg.APIMaxRetries,
+		MinBackoff: time.Duration(cfg.APIMinBackoff) * time.Second,
+		MaxBackoff: time.Duration(cfg.APIMaxBackoff) * time.Second,
+		Timeout:    time.Duration(cfg.APITimeout) * time.Second,
+	}
+	if err := apiPolicy.Validate(); err != nil {
+		return client.RetryPolicy{}, client.RetryPolicy{}, fmt.Errorf("API retry policy: %s", err)
 	}
 
-	var outputs = map[string]string{
-		statusEnvKey:                    "success",
-		"APPCENTER_DEPLOY_INSTALL_URL":  release.InstallURL,
-		"APPCENTER_DEPLOY_DOWNLOAD_URL": release.DownloadURL,
-		"APPCENTER_RELEASE_PAGE_URL":    fmt.Sprintf("https://appcenter.ms/orgs/%s/apps/%s/distribute/releases/%d", cfg.OwnerName, cfg.AppName, release.ID),
-		"APPCENTER_DEPLOY_RELEASE_ID":   strconv.Itoa(release.ID),
+	uploadPolicy := client.RetryPolicy{
+		MaxRetries: cfg.UploadMaxRetries,
+		MinBackoff: time.Duration(cfg.UploadMinBackoff) * time.Second,
+		MaxBackoff: time.Duration(cfg.UploadMaxBackoff) * time.Second,
+		Timeout:    time.Duration(cfg.UploadTimeout) * time.Second,
+	}
+	if err := uploadPolicy.Validate(); err != nil {
+		return client.RetryPolicy{}, client.RetryPolicy{}, fmt.Errorf("upload retry policy: %s", err)
 	}
 
-	if len(publicGroup) > 0 {
-		outputs["APPCENTER_PUBLIC_INSTALL_PAGE_URL"] = fmt.Sprintf("https://install.appcenter.ms/users/%s/apps/%s/distribution_groups/%s", cfg.OwnerName, cfg.AppName, publicGroup[0])
-		outputs["APPCENTER_PUBLIC_INSTALL_PAGE_URLS"] = strings.Join(groupUrls, ", ")
-	} else {
-		outputs["APPCENTER_PUBLIC_INSTALL_PAGE_URL"] = ""
-		outputs["APPCENTER_PUBLIC_INSTALL_PAGE_URLS"] = ""
+	return apiPolicy, uploadPolicy, nil
+}
+
+// runReporting reports the outcome of the run: it writes the deploy report and the HTTP recording,
+// exports the outputs, calls the webhook and advises on errors.
+type runReporting struct {
+	report  *deployReport
+	har     *trafficRecording
+	webhook *webhookNotifier
+	guide   errorGuide
+}
+
+// currentRunReporting returns the reporting of the run, as set up by main.
+func currentRunReporting() runReporting {
+	return runReporting{
+		report:  report,
+		har:     harRecording,
+		webhook: webhook,
+		guide:   guide,
 	}
+}
+
+// exportOutputs exports the outputs with the reporting of the run.
+func exportOutputs(outputs map[string]string) {
+	currentRunReporting().exportOutputs(outputs)
+}
 
-	for key, value := range outputs {
+// exportOutputs writes the deploy report and exports the outputs together with the report's paths.
+func (r runReporting) exportOutputs(outputs map[string]string) {
+	if r.report != nil {
+		jsonPath, markdownPath, err := r.report.write(outputs[statusEnvKey], "", outputs)
+		if err != nil {
+			log.Warnf("Failed to write deploy report, error: %s", err)
+		} else if jsonPath != "" {
+			outputs[reportPathEnvKey] = jsonPath
+			outputs[reportMarkdownPathEnvKey] = markdownPath
+		}
+	}
+
+	if harPath, err := r.har.write(); err != nil {
+		log.Warnf("Failed to write HTTP recording, error: %s", err)
+	} else if harPath != "" {
+		outputs[harPathEnvKey] = harPath
+	}
+
+	for _, key := range sortedKeys(outputs) {
+		value := outputs[key]
 		log.Printf("- %s: %s", key, value)
 		if err := tools.ExportEnvironmentWithEnvman(key, value); err != nil {
 			failf("Failed to export environment variable: %s with value: %s. Error: %s", key, value, err)
 		}
 	}
 
-	log.Donef("- Done")
+	if err := r.notifyWebhook(outputs[statusEnvKey], "", outputs); err != nil {
+		failf("Failed to call webhook, error: %s", err)
+	}
 }
 
 func failf(f string, args ...interface{}) {
-	log.Errorf(f, args...)
+	msg := client.Redact(fmt.Sprintf(f, args...))
+	log.Errorf("%s", msg)
+
+	if advice := guide.advise(args...); advice != "" {
+		log.Warnf("%s", advice)
+		msg += ". " + advice
+	}
+
+	if report != nil {
+		if jsonPath, _, err := report.write("failed", msg, nil); err != nil {
+			log.Warnf("Failed to write deploy report, error: %s", err)
+		} else if jsonPath != "" {
+			if err := tools.ExportEnvironmentWithEnvman(reportPathEnvKey, jsonPath); err != nil {
+				log.Errorf("Failed to export environment variable: %s with value: %s. Error: %s", reportPathEnvKey, jsonPath, err)
+			}
+		}
+	}
+
+	exportHARPath()
 
 	if err := tools.ExportEnvironmentWithEnvman(statusEnvKey, "failed"); err != nil {
 		log.Errorf("Failed to export environment variable: %s with value: %s. Error: %s", statusEnvKey, "failed", err)
 	}
 
+	// The step is failing anyway, webhook errors are only logged.
+	_ = currentRunReporting().notifyWebhook("failed", msg, nil)
+
 	os.Exit(1)
 }
@@ -0,0 +1,483 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/fake"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/model"
+)
+
+// runMainEnvKey makes the test binary run the step's main instead of the tests,
+// so the whole flow can be run in a subprocess, including its os.Exit calls.
+const runMainEnvKey = "APPCENTER_DEPLOY_TEST_RUN_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runMainEnvKey) == "1" {
+		main()
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+// stepRun is the outcome of running the step.
+type stepRun struct {
+	exitCode int
+	// outputs are the environment variables exported through envman.
+	outputs map[string]string
+	log     string
+}
+
+// runStep runs the step against the fake server with the default inputs of step.yml overridden by inputs.
+// envman is replaced by a script writing every exported value into a file named after its key.
+func runStep(t *testing.T, server *fake.Server, inputs map[string]string) stepRun {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("the envman stub is a shell script")
+	}
+
+	tmpDir := t.TempDir()
+	binDir := filepath.Join(tmpDir, "bin")
+	outputsDir := filepath.Join(tmpDir, "outputs")
+	deployDir := filepath.Join(tmpDir, "deploy")
+	for _, dir := range []string{binDir, outputsDir, deployDir} {
+		if err := os.Mkdir(dir, 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	envman := "#!/bin/sh\n# envman add --key KEY, the value is read from the stdin.\ncat > \"$ENVMAN_STUB_DIR/$3\"\n"
+	if err := os.WriteFile(filepath.Join(binDir, "envman"), []byte(envman), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	appPath := filepath.Join(tmpDir, "app-release.apk")
+	if err := os.WriteFile(appPath, bytes.Repeat([]byte("apk"), 1024), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	env := map[string]string{
+		"app_path":                     appPath,
+		"mapping_path":                 "",
+		"api_token":                    fake.Token,
+		"owner_name":                   "owner",
+		"app_name":                     "app",
+		"distribution_group":           "",
+		"distribution_store":           "",
+		"distribution_tester":          "",
+		"all_distribution_groups":      "no",
+		"release_notes":                "Release notes",
+		"release_notes_source":         "text",
+		"release_notes_template":       "no",
+		"notify_testers":               "yes",
+		"mandatory":                    "no",
+		"dry_run":                      "no",
+		"deploy_dir":                   deployDir,
+		"mode":                         "deploy",
+		"cleanup_action":               "disable",
+		"webhook_on":                   "always",
+		"max_size_increase_percent":    "0",
+		"max_size_increase_bytes":      "0",
+		"api_base_url":                 server.URL,
+		"portal_base_url":              "https://appcenter.ms",
+		"install_base_url":             "https://install.appcenter.ms",
+		"debug":                        "no",
+		"distribution_concurrency":     "4",
+		"fail_mode":                    "fail_fast",
+		"fail_on_partial_distribution": "no",
+		// Scripted failures are not retried, unless a test asks for it.
+		"api_max_retries":    "0",
+		"api_min_backoff":    "0",
+		"api_max_backoff":    "0",
+		"api_timeout":        "10",
+		"upload_max_retries": "0",
+		"upload_min_backoff": "0",
+		"upload_max_backoff": "0",
+		"upload_timeout":     "10",
+		// The uploads of the fake are ready at the first check.
+		"upload_status_max_attempts": "1",
+	}
+	for key, value := range inputs {
+		env[key] = value
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	cmd.Env = append(os.Environ(),
+		runMainEnvKey+"=1",
+		"ENVMAN_STUB_DIR="+outputsDir,
+		"PATH="+binDir+string(os.PathListSeparator)+os.Getenv("PATH"),
+	)
+	for key, value := range env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+
+	out, err := cmd.CombinedOutput()
+	run := stepRun{outputs: map[string]string{}, log: string(out)}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		run.exitCode = exitErr.ExitCode()
+	} else if err != nil {
+		t.Fatalf("failed to run the step: %s", err)
+	}
+
+	entries, err := os.ReadDir(outputsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		value, err := os.ReadFile(filepath.Join(outputsDir, entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		run.outputs[entry.Name()] = string(value)
+	}
+
+	return run
+}
+
+// requested returns the "METHOD path" of the requests received by the server, in order.
+func requested(server *fake.Server) []string {
+	var requests []string
+	for _, request := range server.Requests() {
+		requests = append(requests, request.Method+" "+request.Path)
+	}
+
+	return requests
+}
+
+func containsRequest(requests []string, request string) bool {
+	for _, r := range requests {
+		if r == request {
+			return true
+		}
+	}
+
+	return false
+}
+
+func Test_main_deploy(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	app := server.AddApp("owner", "app")
+	app.AddGroup("Collaborators", false)
+	app.AddGroup("Public Testers", true)
+	app.AddStore("Production", "googleplay")
+
+	mappingPath := filepath.Join(t.TempDir(), "mapping.txt")
+	if err := os.WriteFile(mappingPath, []byte("com.example.A -> a:"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	run := runStep(t, server, map[string]string{
+		"distribution_group":  "Collaborators\nPublic Testers",
+		"distribution_store":  "Production",
+		"distribution_tester": "tester@example.com",
+		"mapping_path":        mappingPath,
+		"release_notes":       "Fixes {{ .Version }}",
+	})
+	if run.exitCode != 0 {
+		t.Fatalf("step failed with exit code %d:\n%s", run.exitCode, run.log)
+	}
+
+	releaseID, err := strconv.Atoi(run.outputs["APPCENTER_DEPLOY_RELEASE_ID"])
+	if err != nil {
+		t.Fatalf("invalid release ID output: %s", err)
+	}
+
+	wantOutputs := map[string]string{
+		"APPCENTER_DEPLOY_STATUS":                "success",
+		"APPCENTER_DEPLOY_FAILED_DESTINATIONS":   "[]",
+		"APPCENTER_DEPLOY_RELEASE_NOTES":         "Fixes {{ .Version }}",
+		"APPCENTER_DEPLOY_DESTINATIONS":          "Collaborators,Public Testers,Production,tester@example.com",
+		"APPCENTER_DEPLOY_SHORT_VERSION":         "1.0",
+		"APPCENTER_RELEASE_PAGE_URL":             "https://appcenter.ms/users/owner/apps/app/distribute/releases/" + strconv.Itoa(releaseID),
+		"APPCENTER_PUBLIC_INSTALL_PAGE_URL":      "https://install.appcenter.ms/users/owner/apps/app/distribution_groups/Public%20Testers",
+		"APPCENTER_PUBLIC_INSTALL_PAGE_URLS":     "https://install.appcenter.ms/users/owner/apps/app/distribution_groups/Public%20Testers",
+		"APPCENTER_DEPLOY_ANDROID_MIN_API_LEVEL": "21",
+	}
+	for key, want := range wantOutputs {
+		if got := run.outputs[key]; got != want {
+			t.Errorf("output %s = %q, want %q", key, got, want)
+		}
+	}
+	if !strings.HasPrefix(run.outputs["APPCENTER_DEPLOY_RELEASE_COMPARISON"], `{"previous_release_id":0,`) {
+		t.Errorf("output APPCENTER_DEPLOY_RELEASE_COMPARISON = %q, want a comparison without a previous release", run.outputs["APPCENTER_DEPLOY_RELEASE_COMPARISON"])
+	}
+
+	release, ok := server.Release("owner", "app", releaseID)
+	if !ok {
+		t.Fatalf("release %d not found", releaseID)
+	}
+	// The destinations are added in parallel.
+	sort.Strings(release.Groups)
+	if want := []string{"Collaborators", "Public Testers"}; !reflect.DeepEqual(release.Groups, want) {
+		t.Errorf("release groups = %v, want %v", release.Groups, want)
+	}
+	if want := []string{"Production"}; !reflect.DeepEqual(release.Stores, want) {
+		t.Errorf("release stores = %v, want %v", release.Stores, want)
+	}
+	if want := []string{"tester@example.com"}; !reflect.DeepEqual(release.Testers, want) {
+		t.Errorf("release testers = %v, want %v", release.Testers, want)
+	}
+	if len(release.SymbolUploads) != 1 {
+		t.Errorf("release symbol uploads = %v, want 1", release.SymbolUploads)
+	}
+	if release.ReleaseNotes != "Fixes {{ .Version }}" {
+		t.Errorf("release notes = %q, want the plain text", release.ReleaseNotes)
+	}
+
+	requests := requested(server)
+	for _, want := range []string{
+		"GET /v0.1/apps/owner/app",
+		"GET /v0.1/apps/owner/app/distribution_groups",
+		"POST /v0.1/apps/owner/app/uploads/releases",
+		"PUT /v0.1/apps/owner/app/releases/" + strconv.Itoa(releaseID),
+		"POST /v0.1/apps/owner/app/releases/" + strconv.Itoa(releaseID) + "/groups",
+		"POST /v0.1/apps/owner/app/releases/" + strconv.Itoa(releaseID) + "/stores",
+		"POST /v0.1/apps/owner/app/releases/" + strconv.Itoa(releaseID) + "/testers",
+		"POST /v0.1/apps/owner/app/symbol_uploads",
+	} {
+		if !containsRequest(requests, want) {
+			t.Errorf("request %q not sent, got: %v", want, requests)
+		}
+	}
+}
+
+func Test_main_failures(t *testing.T) {
+	tests := []struct {
+		name         string
+		inputs       map[string]string
+		setup        func(server *fake.Server)
+		wantExitCode int
+		wantStatus   string
+		wantFailed   string
+		wantGroups   []string
+		wantNoUpload bool
+		// wantLog is a line the log has to contain.
+		wantLog string
+	}{
+		{
+			name:       "continue mode exports a partial distribution",
+			inputs:     map[string]string{"fail_mode": "continue", "distribution_tester": "tester@example.com"},
+			setup:      func(server *fake.Server) { server.Fail("POST", "/testers$", 500, 0) },
+			wantStatus: "partial",
+			wantFailed: `"name":"tester@example.com"`,
+			wantGroups: []string{"Collaborators"},
+		},
+		{
+			name:       "continue mode advises on a failed destination",
+			inputs:     map[string]string{"fail_mode": "continue", "distribution_group": "Collaborators\nNope"},
+			wantStatus: "partial",
+			wantFailed: `"name":"Nope"`,
+			wantGroups: []string{"Collaborators"},
+			wantLog:    "- group Nope: Distribution group (Nope) does not exist in app (owner/app). Available groups: Collaborators.",
+		},
+		{
+			name:         "fail_on_partial_distribution fails the partial distribution",
+			inputs:       map[string]string{"fail_mode": "continue", "fail_on_partial_distribution": "yes", "distribution_tester": "tester@example.com"},
+			setup:        func(server *fake.Server) { server.Fail("POST", "/testers$", 500, 0) },
+			wantExitCode: 1,
+			wantStatus:   "partial",
+			wantGroups:   []string{"Collaborators"},
+		},
+		{
+			name:         "fail_fast mode fails on an unknown group",
+			inputs:       map[string]string{"distribution_group": "Colaborators", "distribution_concurrency": "1"},
+			wantExitCode: 1,
+			wantStatus:   "failed",
+		},
+		{
+			name:         "unknown compare_group fails before the upload",
+			inputs:       map[string]string{"compare_group": "Colaborators"},
+			wantExitCode: 1,
+			wantStatus:   "failed",
+			wantNoUpload: true,
+		},
+		{
+			name:         "dry run does not upload",
+			inputs:       map[string]string{"dry_run": "yes"},
+			wantStatus:   "dry_run",
+			wantNoUpload: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := fake.NewServer()
+			defer server.Close()
+
+			app := server.AddApp("owner", "app")
+			app.AddGroup("Collaborators", false)
+			if tt.setup != nil {
+				tt.setup(server)
+			}
+
+			inputs := map[string]string{"distribution_group": "Collaborators"}
+			for key, value := range tt.inputs {
+				inputs[key] = value
+			}
+
+			run := runStep(t, server, inputs)
+			if run.exitCode != tt.wantExitCode {
+				t.Fatalf("exit code = %d, want %d:\n%s", run.exitCode, tt.wantExitCode, run.log)
+			}
+			if got := run.outputs["APPCENTER_DEPLOY_STATUS"]; got != tt.wantStatus {
+				t.Errorf("output APPCENTER_DEPLOY_STATUS = %q, want %q", got, tt.wantStatus)
+			}
+			if got := run.outputs["APPCENTER_DEPLOY_FAILED_DESTINATIONS"]; !strings.Contains(got, tt.wantFailed) {
+				t.Errorf("output APPCENTER_DEPLOY_FAILED_DESTINATIONS = %q, want it to contain %q", got, tt.wantFailed)
+			}
+			if !strings.Contains(run.log, tt.wantLog) {
+				t.Errorf("log does not contain %q:\n%s", tt.wantLog, run.log)
+			}
+
+			uploaded := containsRequest(requested(server), "POST /v0.1/apps/owner/app/uploads/releases")
+			if uploaded == tt.wantNoUpload {
+				t.Errorf("uploaded = %t, want %t", uploaded, !tt.wantNoUpload)
+			}
+
+			if tt.wantGroups != nil {
+				release, ok := server.Release("owner", "app", 1)
+				if !ok {
+					t.Fatalf("release 1 not found")
+				}
+				if !reflect.DeepEqual(release.Groups, tt.wantGroups) {
+					t.Errorf("release groups = %v, want %v", release.Groups, tt.wantGroups)
+				}
+			}
+		})
+	}
+}
+
+func Test_main_overBudget(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+
+	app := server.AddApp("owner", "app")
+	app.AddGroup("Collaborators", false)
+	app.Releases = append(app.Releases, &fake.Release{
+		Release: model.Release{ID: 1, Version: "1", ShortVersion: "1.0", Enabled: true, AndroidMinAPILevel: "19"},
+		Groups:  []string{"Collaborators"},
+	})
+
+	run := runStep(t, server, map[string]string{"distribution_group": "Collaborators"})
+	if run.exitCode != 1 {
+		t.Fatalf("exit code = %d, want 1:\n%s", run.exitCode, run.log)
+	}
+	if want := "release (2) is uploaded but disabled and not distributed, it exceeds the budget: min API level changed from 19 to 21"; !strings.Contains(run.log, want) {
+		t.Errorf("log does not contain %q:\n%s", want, run.log)
+	}
+
+	release, ok := server.Release("owner", "app", 2)
+	if !ok {
+		t.Fatalf("release 2 not found")
+	}
+	if release.Enabled {
+		t.Errorf("release enabled = true, want the release over budget disabled")
+	}
+	if len(release.Groups) != 0 {
+		t.Errorf("release groups = %v, want no distribution", release.Groups)
+	}
+}
+
+func Test_main_withoutDeployDir(t *testing.T) {
+	for _, tt := range []struct {
+		name         string
+		inputs       map[string]string
+		wantExitCode int
+	}{
+		{name: "success", inputs: map[string]string{"distribution_group": "Collaborators"}},
+		{name: "failure", inputs: map[string]string{"distribution_group": "Colaborators"}, wantExitCode: 1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			server := fake.NewServer()
+			defer server.Close()
+
+			server.AddApp("owner", "app").AddGroup("Collaborators", false)
+
+			inputs := map[string]string{"deploy_dir": ""}
+			for key, value := range tt.inputs {
+				inputs[key] = value
+			}
+
+			run := runStep(t, server, inputs)
+			if run.exitCode != tt.wantExitCode {
+				t.Fatalf("exit code = %d, want %d:\n%s", run.exitCode, tt.wantExitCode, run.log)
+			}
+			if strings.Contains(run.log, "Failed to write deploy report") {
+				t.Errorf("log warns about the deploy report without a deploy directory:\n%s", run.log)
+			}
+			if tt.wantExitCode == 0 && !strings.Contains(run.log, "Scan to install:") {
+				t.Errorf("log does not contain the install QR code without a deploy directory:\n%s", run.log)
+			}
+			for _, key := range []string{"APPCENTER_DEPLOY_REPORT_PATH", "APPCENTER_DEPLOY_REPORT_MARKDOWN_PATH"} {
+				if value, ok := run.outputs[key]; ok {
+					t.Errorf("output %s = %q, want it not exported", key, value)
+				}
+			}
+		})
+	}
+}
+
+func Test_checkRequiredInputs(t *testing.T) {
+	step, err := os.ReadFile("step.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every input marked as required in step.yml has to be required by the config.
+	inputPattern := regexp.MustCompile(`^\s*- ([a-z_]+):`)
+	var input string
+	for _, line := range strings.Split(string(step), "\n") {
+		if match := inputPattern.FindStringSubmatch(line); match != nil {
+			input = match[1]
+		}
+		if strings.TrimSpace(line) != "is_required: true" {
+			continue
+		}
+
+		env := map[string]string{input: ""}
+		field, ok := configField(input)
+		if !ok {
+			t.Errorf("required input %s is not in the config", input)
+			continue
+		}
+		if strings.HasSuffix(field.Tag.Get("env"), ",required") {
+			continue
+		}
+		if err := checkRequiredInputs(config{}, func(key string) string {
+			if value, ok := env[key]; ok {
+				return value
+			}
+			return "set"
+		}); err == nil || !strings.Contains(err.Error(), input) {
+			t.Errorf("checkRequiredInputs() error = %v, want %s to be required", err, input)
+		}
+	}
+
+	if err := checkRequiredInputs(config{}, func(string) string { return "set" }); err != nil {
+		t.Errorf("checkRequiredInputs() error = %v, want nil when every input is set", err)
+	}
+}
+
+// configField returns the config field of the input.
+func configField(input string) (reflect.StructField, bool) {
+	t := reflect.TypeOf(config{})
+	for i := 0; i < t.NumField(); i++ {
+		if strings.SplitN(t.Field(i).Tag.Get("env"), ",", 2)[0] == input {
+			return t.Field(i), true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/client"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/model"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/deployer"
+)
+
+const (
+	modeDeploy  = "deploy"
+	modePromote = "promote"
+)
+
+// resolvePromotedRelease returns the release given by release_id, or the latest release of promote_from_group.
+// The group is looked up among the app's groups first, as the latest release of an unknown group is reported as no release.
+func resolvePromotedRelease(cfg config, appAPI appcenter.AppAPI, groups []model.Group) (model.Release, error) {
+	switch {
+	case cfg.ReleaseID > 0:
+		return appAPI.ReleaseDetails(cfg.ReleaseID)
+	case cfg.PromoteFromGroup != "":
+		group, err := deployer.LookupGroup(groups, cfg.PromoteFromGroup)
+		if err != nil {
+			return model.Release{}, fmt.Errorf("promote_from_group (%s): %w", cfg.PromoteFromGroup, err)
+		}
+
+		release, err := appAPI.LatestReleaseInGroup(group.Name)
+		if err != nil {
+			return model.Release{}, err
+		}
+
+		if release.ID == 0 {
+			return model.Release{}, fmt.Errorf("no release was distributed to group (%s) yet", cfg.PromoteFromGroup)
+		}
+
+		// The latest release endpoint of a group does not return every detail of the release.
+		return appAPI.ReleaseDetails(release.ID)
+	default:
+		return model.Release{}, fmt.Errorf("either release_id or promote_from_group is required in promote mode")
+	}
+}
+
+// promote adds an existing release to the configured destinations without uploading a new binary.
+func promote(cfg config, api client.API, appAPI appcenter.AppAPI, releaseOptions model.ReleaseOptions, urls appURLs, groups []model.Group, plan deployer.Plan) {
+	log.Infof("Fetching the release to promote")
+
+	release, err := resolvePromotedRelease(cfg, appAPI, groups)
+	if err != nil {
+		failf("Failed to fetch the release to promote, error: %s", err)
+	}
+
+	report.Release = &release
+
+	log.Printf("- Release: %d (%s (%s))", release.ID, release.ShortVersion, release.Version)
+	log.Printf("- Uploaded at: %s", release.UploadedAt)
+	log.Donef("- Done")
+	fmt.Println()
+
+	if cfg.DryRun {
+		log.Infof("Promote plan")
+		log.Printf("- Mandatory: %t, notify testers: %t", cfg.Mandatory, cfg.NotifyTesters)
+		for _, planned := range plan.Groups {
+			log.Printf("- Group: %s (found: %t, public: %t)", planned.Name, planned.Found, planned.Group.IsPublic)
+		}
+		for _, storeName := range plan.Stores {
+			log.Printf("- Store: %s", storeName)
+		}
+		for _, email := range plan.Testers {
+			log.Printf("- Tester: %s", email)
+		}
+		fmt.Println()
+
+		log.Infof("Exporting outputs")
+		exportOutputs(map[string]string{statusEnvKey: dryRunStatus})
+		log.Donef("- Done")
+		log.Warnf("Dry run: the release was not distributed")
+		return
+	}
+
+	deps := appCenterDeps{
+		api:            api,
+		releaseOptions: releaseOptions,
+	}
+	d := deployer.Deployer{
+		Resolver:    deps,
+		Distributor: deps,
+		Phases:      report,
+	}
+
+	summary, err := d.Distribute(context.Background(), newDeployerConfig(cfg), release, plan)
+	report.Destinations = summary.Results
+	if err != nil {
+		failf("Promote failed, error: %s", err)
+	}
+
+	result := deployer.Result{
+		Status:  summary.Status(),
+		Release: release,
+		Plan:    plan,
+		Summary: summary,
+	}
+	if err := exportReleaseOutputs(cfg, currentRunReporting(), result, urls, release.ReleaseNotes); err != nil {
+		failf("Failed to export outputs, error: %s", err)
+	}
+
+	exitOnPartialDistribution(cfg, summary, guide)
+}
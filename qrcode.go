@@ -0,0 +1,147 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/model"
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	qrCodePNGSize    = 512
+	qrCodeSVGModSize = 8
+
+	installQRCodeFileName = "appcenter-install-qr"
+
+	installQRCodePNGPathEnvKey            = "APPCENTER_DEPLOY_INSTALL_QR_CODE_PNG_PATH"
+	installQRCodeSVGPathEnvKey            = "APPCENTER_DEPLOY_INSTALL_QR_CODE_SVG_PATH"
+	publicInstallPageQRCodePNGPathsEnvKey = "APPCENTER_PUBLIC_INSTALL_PAGE_QR_CODE_PNG_PATHS"
+	publicInstallPageQRCodeSVGPathsEnvKey = "APPCENTER_PUBLIC_INSTALL_PAGE_QR_CODE_SVG_PATHS"
+)
+
+var qrCodeFileNameUnsafeChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// qrCode is a QR code of a URL, written to the deploy directory as PNG and SVG.
+type qrCode struct {
+	code *qrcode.QRCode
+}
+
+func newQRCode(content string) (qrCode, error) {
+	code, err := qrcode.New(content, qrcode.Medium)
+	if err != nil {
+		return qrCode{}, err
+	}
+
+	return qrCode{code: code}, nil
+}
+
+// write saves the QR code as <name>.png and <name>.svg to dir and returns their paths.
+func (q qrCode) write(dir, name string) (string, string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", "", err
+	}
+
+	pngPath := filepath.Join(dir, name+".png")
+	if err := q.code.WriteFile(qrCodePNGSize, pngPath); err != nil {
+		return "", "", err
+	}
+
+	svgPath := filepath.Join(dir, name+".svg")
+	if err := os.WriteFile(svgPath, []byte(q.svg()), 0644); err != nil {
+		return "", "", err
+	}
+
+	return pngPath, svgPath, nil
+}
+
+// svg renders the QR code (including the quiet zone) as an SVG image, one rect per dark module.
+func (q qrCode) svg() string {
+	bitmap := q.code.Bitmap()
+	size := len(bitmap) * qrCodeSVGModSize
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, size, size, size, size)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#ffffff"/>`, size, size)
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="#000000"/>`, x*qrCodeSVGModSize, y*qrCodeSVGModSize, qrCodeSVGModSize, qrCodeSVGModSize)
+			}
+		}
+	}
+	b.WriteString("</svg>\n")
+
+	return b.String()
+}
+
+// terminal renders the QR code with block characters, two modules per line, so it can be scanned from the build log.
+func (q qrCode) terminal() string {
+	return q.code.ToSmallString(false)
+}
+
+// qrCodeFileName returns a file name for the QR code of the named item, unique even if names only differ in unsafe characters.
+func qrCodeFileName(prefix, name string) string {
+	sum := sha256.Sum256([]byte(name))
+
+	return fmt.Sprintf("%s-%s-%x", prefix, strings.Trim(qrCodeFileNameUnsafeChars.ReplaceAllString(name, "_"), "_"), sum[:4])
+}
+
+// setQRCodeOutputs prints the install URL's QR code, renders QR codes for the install URL and the public install pages
+// into the deploy directory (if set) and fills the QR code outputs.
+// QR codes are a convenience, errors are only logged.
+func setQRCodeOutputs(outputs map[string]string, deployDir string, release model.Release, urls appURLs, publicGroups []model.Group) {
+	outputs[installQRCodePNGPathEnvKey] = ""
+	outputs[installQRCodeSVGPathEnvKey] = ""
+	outputs[publicInstallPageQRCodePNGPathsEnvKey] = ""
+	outputs[publicInstallPageQRCodeSVGPathsEnvKey] = ""
+
+	if release.InstallURL != "" {
+		code, err := newQRCode(release.InstallURL)
+		if err != nil {
+			log.Warnf("Failed to generate QR code of the install URL, error: %s", err)
+		} else {
+			log.Printf("Scan to install:")
+			fmt.Println(code.terminal())
+
+			if deployDir != "" {
+				if pngPath, svgPath, err := code.write(deployDir, installQRCodeFileName); err != nil {
+					log.Warnf("Failed to write QR code of the install URL, error: %s", err)
+				} else {
+					outputs[installQRCodePNGPathEnvKey] = pngPath
+					outputs[installQRCodeSVGPathEnvKey] = svgPath
+				}
+			}
+		}
+	}
+
+	if deployDir == "" {
+		return
+	}
+
+	var pngPaths, svgPaths []string
+	for _, group := range publicGroups {
+		code, err := newQRCode(urls.publicInstallPage(group))
+		if err != nil {
+			log.Warnf("Failed to generate QR code of the public install page of group (%s), error: %s", group.DisplayName, err)
+			continue
+		}
+
+		pngPath, svgPath, err := code.write(deployDir, qrCodeFileName(installQRCodeFileName, group.Name))
+		if err != nil {
+			log.Warnf("Failed to write QR code of the public install page of group (%s), error: %s", group.DisplayName, err)
+			continue
+		}
+
+		pngPaths = append(pngPaths, pngPath)
+		svgPaths = append(svgPaths, svgPath)
+	}
+
+	outputs[publicInstallPageQRCodePNGPathsEnvKey] = strings.Join(pngPaths, ", ")
+	outputs[publicInstallPageQRCodeSVGPathsEnvKey] = strings.Join(svgPaths, ", ")
+}
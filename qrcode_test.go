@@ -0,0 +1,26 @@
+package main
+
+import (
+	"regexp"
+	"testing"
+)
+
+func Test_qrCodeFileName(t *testing.T) {
+	names := []string{"public-testers", "public testers", "public_testers", "Public Testers", "beta/testers", "beta:testers"}
+
+	seen := map[string]string{}
+	for _, name := range names {
+		got := qrCodeFileName(installQRCodeFileName, name)
+		if !regexp.MustCompile(`^appcenter-install-qr-[A-Za-z0-9._-]+-[0-9a-f]{8}$`).MatchString(got) {
+			t.Errorf("qrCodeFileName(%q) = %q, want a safe file name with a hash suffix", name, got)
+		}
+		if other, ok := seen[got]; ok {
+			t.Errorf("qrCodeFileName(%q) = qrCodeFileName(%q) = %q, want unique file names", name, other, got)
+		}
+		seen[got] = name
+
+		if again := qrCodeFileName(installQRCodeFileName, name); again != got {
+			t.Errorf("qrCodeFileName(%q) = %q, then %q, want a stable file name", name, got, again)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-utils/retry"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/model"
+)
+
+const (
+	// releaseNotesMaxLength is the maximum number of characters App Center accepts as release notes.
+	releaseNotesMaxLength       = 5000
+	releaseNotesTruncatedMarker = "…(truncated)"
+
+	releaseNotesCheckRetries  = 10
+	releaseNotesCheckWaitTime = 3 * time.Second
+)
+
+// releaseNotesEnvKeys are the environment variables available for the release notes template,
+// together with the ones starting with releaseNotesEnvPrefix. Other variables, like secrets, are not exposed.
+var releaseNotesEnvKeys = []string{
+	"BITRISE_APP_TITLE",
+	"BITRISE_APP_URL",
+	"BITRISE_BUILD_NUMBER",
+	"BITRISE_BUILD_URL",
+	"BITRISE_GIT_BRANCH",
+	"BITRISE_GIT_COMMIT",
+	"BITRISE_GIT_MESSAGE",
+	"BITRISE_GIT_TAG",
+	"BITRISE_PULL_REQUEST",
+	"BITRISE_TRIGGERED_WORKFLOW_ID",
+	"BITRISEIO_GIT_BRANCH_DEST",
+	"GIT_CLONE_COMMIT_AUTHOR_NAME",
+	"GIT_CLONE_COMMIT_HASH",
+	"GIT_CLONE_COMMIT_MESSAGE_SUBJECT",
+}
+
+// releaseNotesEnvPrefix marks custom environment variables as available for the release notes template.
+const releaseNotesEnvPrefix = "RELEASE_NOTES_"
+
+// releaseNotesData is available for the release notes template.
+type releaseNotesData struct {
+	// Version is the version name App Center reports for the release.
+	Version string
+	// BuildNumber is the version code App Center reports for the release.
+	BuildNumber string
+	// Env contains the environment variables of the build available for the template, see releaseNotesEnv.
+	Env map[string]string
+}
+
+func newReleaseNotesData(release model.Release) releaseNotesData {
+	env := map[string]string{}
+	for _, kv := range os.Environ() {
+		if key, value, ok := strings.Cut(kv, "="); ok && isReleaseNotesEnvKey(key) {
+			env[key] = value
+		}
+	}
+
+	return releaseNotesData{
+		Version:     release.ShortVersion,
+		BuildNumber: release.Version,
+		Env:         env,
+	}
+}
+
+func isReleaseNotesEnvKey(key string) bool {
+	if strings.HasPrefix(key, releaseNotesEnvPrefix) {
+		return true
+	}
+
+	for _, allowed := range releaseNotesEnvKeys {
+		if key == allowed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// releaseNotesEnv returns the value of an environment variable available for the template, or empty for any other one.
+func releaseNotesEnv(key string) string {
+	if !isReleaseNotesEnvKey(key) {
+		return ""
+	}
+
+	return os.Getenv(key)
+}
+
+// releaseNotesInput is either a release notes template, plain release notes or release notes generated upfront.
+type releaseNotesInput struct {
+	text       string
+	isTemplate bool
+}
+
+// render executes the template with the version of the release.
+func (n releaseNotesInput) render(release model.Release) (string, error) {
+	if !n.isTemplate {
+		return n.text, nil
+	}
+
+	return renderReleaseNotes(n.text, newReleaseNotesData(release))
+}
+
+// loadReleaseNotes returns the release notes template, the release notes file takes precedence over the inline text.
+func loadReleaseNotes(inline, filePath string) (string, error) {
+	if filePath == "" {
+		return inline, nil
+	}
+
+	b, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read release notes file: %s", err)
+	}
+
+	return string(b), nil
+}
+
+func parseReleaseNotes(text string) (*template.Template, error) {
+	tmpl, err := template.New("release_notes").Option("missingkey=zero").Funcs(template.FuncMap{
+		"env": releaseNotesEnv,
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid release notes template: %s", err)
+	}
+
+	return tmpl, nil
+}
+
+// renderReleaseNotes executes the release notes template, the text is otherwise kept as is (including Markdown).
+func renderReleaseNotes(text string, data releaseNotesData) (string, error) {
+	tmpl, err := parseReleaseNotes(text)
+	if err != nil {
+		return "", err
+	}
+
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render release notes template: %s", err)
+	}
+
+	return b.String(), nil
+}
+
+// truncateReleaseNotes shortens the notes to at most max characters, ending with a truncation marker.
+// The text is cut at the last line break or space before the limit if there is one, so words are not split.
+func truncateReleaseNotes(notes string, max int) (string, bool) {
+	if utf8.RuneCountInString(notes) <= max {
+		return notes, false
+	}
+
+	limit := max - utf8.RuneCountInString(releaseNotesTruncatedMarker) - 1
+	if limit < 0 {
+		limit = 0
+	}
+
+	truncated := string([]rune(notes)[:limit])
+	if idx := strings.LastIndexAny(truncated, "\n "); idx > len(truncated)/2 {
+		truncated = truncated[:idx]
+	}
+
+	return strings.TrimRight(truncated, " \n") + "\n" + releaseNotesTruncatedMarker, true
+}
+
+// waitForReleaseNotes re-fetches the release until App Center returns the expected release notes.
+func waitForReleaseNotes(releaseAPI appcenter.ReleaseAPI, expected string) error {
+	return retry.Times(releaseNotesCheckRetries).Wait(releaseNotesCheckWaitTime).Try(func(attempt uint) error {
+		if attempt > 0 {
+			log.Printf("Checking release notes, attempt: %d", attempt)
+		}
+
+		release, err := releaseAPI.Details()
+		if err != nil {
+			return fmt.Errorf("failed to fetch release details: %w", err)
+		}
+
+		if normalizeReleaseNotes(release.ReleaseNotes) != normalizeReleaseNotes(expected) {
+			return fmt.Errorf("release notes of release %d do not match the expected ones", release.ID)
+		}
+
+		return nil
+	})
+}
+
+func normalizeReleaseNotes(notes string) string {
+	return strings.TrimSpace(strings.ReplaceAll(notes, "\r\n", "\n"))
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/model"
+)
+
+func Test_releaseNotesInput_render(t *testing.T) {
+	t.Setenv("BITRISE_GIT_BRANCH", "main")
+	t.Setenv("RELEASE_NOTES_AUDIENCE", "QA")
+	t.Setenv("APPCENTER_API_TOKEN", "secret-token")
+
+	release := model.Release{ShortVersion: "9.9.9", Version: "99"}
+
+	tests := []struct {
+		name    string
+		notes   releaseNotesInput
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "plain notes are sent as is",
+			notes: releaseNotesInput{text: "Use `{{ .Name }}` in templates"},
+			want:  "Use `{{ .Name }}` in templates",
+		},
+		{
+			name:  "template uses the release's version",
+			notes: releaseNotesInput{text: "{{ .Version }} ({{ .BuildNumber }})", isTemplate: true},
+			want:  "9.9.9 (99)",
+		},
+		{
+			name:  "template reads the allowed environment variables",
+			notes: releaseNotesInput{text: `{{ .Env.BITRISE_GIT_BRANCH }} {{ env "RELEASE_NOTES_AUDIENCE" }}`, isTemplate: true},
+			want:  "main QA",
+		},
+		{
+			name:  "template does not expose other environment variables",
+			notes: releaseNotesInput{text: `[{{ index .Env "APPCENTER_API_TOKEN" }}][{{ env "APPCENTER_API_TOKEN" }}]`, isTemplate: true},
+			want:  "[][]",
+		},
+		{
+			name:    "invalid template",
+			notes:   releaseNotesInput{text: "{{ .Version", isTemplate: true},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.notes.render(release)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("render() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_truncateReleaseNotes(t *testing.T) {
+	words := strings.Repeat("word ", releaseNotesMaxLength/5+100)
+
+	tests := []struct {
+		name          string
+		notes         string
+		want          string
+		wantTruncated bool
+	}{
+		{
+			name:  "short notes are kept",
+			notes: "Fixed the crash",
+			want:  "Fixed the crash",
+		},
+		{
+			name:  "notes at the limit are kept",
+			notes: strings.Repeat("a", releaseNotesMaxLength),
+			want:  strings.Repeat("a", releaseNotesMaxLength),
+		},
+		{
+			name:          "notes over the limit are cut at a word boundary",
+			notes:         words,
+			want:          strings.TrimSpace(strings.Repeat("word ", (releaseNotesMaxLength-utf8.RuneCountInString(releaseNotesTruncatedMarker)-1)/5)) + "\n" + releaseNotesTruncatedMarker,
+			wantTruncated: true,
+		},
+		{
+			name:          "notes without word boundary are cut at the limit",
+			notes:         strings.Repeat("é", releaseNotesMaxLength+1),
+			want:          strings.Repeat("é", releaseNotesMaxLength-utf8.RuneCountInString(releaseNotesTruncatedMarker)-1) + "\n" + releaseNotesTruncatedMarker,
+			wantTruncated: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, truncated := truncateReleaseNotes(tt.notes, releaseNotesMaxLength)
+			if truncated != tt.wantTruncated {
+				t.Errorf("truncateReleaseNotes() truncated = %t, want %t", truncated, tt.wantTruncated)
+			}
+			if got != tt.want {
+				t.Errorf("truncateReleaseNotes() = %q, want %q", got, tt.want)
+			}
+			if length := utf8.RuneCountInString(got); length > releaseNotesMaxLength {
+				t.Errorf("truncateReleaseNotes() = %d characters, want at most %d", length, releaseNotesMaxLength)
+			}
+		})
+	}
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-steputils/stepconf"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/model"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/deployer"
+)
+
+const (
+	reportJSONFileName     = "appcenter-deploy-report.json"
+	reportMarkdownFileName = "appcenter-deploy-report.md"
+
+	reportPathEnvKey         = "APPCENTER_DEPLOY_REPORT_PATH"
+	reportMarkdownPathEnvKey = "APPCENTER_DEPLOY_REPORT_MARKDOWN_PATH"
+
+	redactedValue = "[REDACTED]"
+)
+
+type reportArtifact struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+type reportSymbolUpload struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type reportPhase struct {
+	Name       string `json:"name"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// deployReport is the audit trail of a step run, written to the deploy directory as JSON and Markdown.
+type deployReport struct {
+	Status        string                       `json:"status"`
+	Error         string                       `json:"error,omitempty"`
+	Mode          string                       `json:"mode"`
+	StartedAt     time.Time                    `json:"started_at"`
+	FinishedAt    time.Time                    `json:"finished_at"`
+	Inputs        map[string]string            `json:"inputs"`
+	Artifacts     []reportArtifact             `json:"artifacts,omitempty"`
+	Release       *model.Release               `json:"release,omitempty"`
+	Comparison    *releaseComparison           `json:"comparison,omitempty"`
+	Destinations  []deployer.DestinationResult `json:"destinations,omitempty"`
+	SymbolUploads []reportSymbolUpload         `json:"symbol_uploads,omitempty"`
+	Phases        []reportPhase                `json:"phases"`
+	Outputs       map[string]string            `json:"outputs,omitempty"`
+
+	deployDir string
+}
+
+// report is the report of the current run, it is nil until the inputs are parsed.
+var report *deployReport
+
+func newDeployReport(cfg config) *deployReport {
+	return &deployReport{
+		Mode:      cfg.Mode,
+		StartedAt: time.Now(),
+		Inputs:    redactedInputs(cfg),
+		deployDir: cfg.DeployDir,
+	}
+}
+
+// redactedInputs returns the step inputs by their keys, with the sensitive ones masked.
+func redactedInputs(cfg interface{}) map[string]string {
+	inputs := map[string]string{}
+
+	v := reflect.ValueOf(cfg)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key, _, _ := strings.Cut(t.Field(i).Tag.Get("env"), ",")
+		if key == "" {
+			continue
+		}
+
+		field := v.Field(i)
+		if _, ok := field.Interface().(stepconf.Secret); ok {
+			if field.String() != "" {
+				inputs[key] = redactedValue
+			} else {
+				inputs[key] = ""
+			}
+			continue
+		}
+
+		inputs[key] = fmt.Sprint(field.Interface())
+	}
+
+	return inputs
+}
+
+// Phase starts measuring a phase of the run, the returned function stops it.
+func (r *deployReport) Phase(name string) func() {
+	start := time.Now()
+
+	return func() {
+		r.Phases = append(r.Phases, reportPhase{
+			Name:       name,
+			DurationMS: time.Since(start).Milliseconds(),
+		})
+	}
+}
+
+func (r *deployReport) addArtifact(path string) error {
+	size, hash, err := fileSHA256(path)
+	if err != nil {
+		return err
+	}
+
+	r.Artifacts = append(r.Artifacts, reportArtifact{Path: path, Size: size, SHA256: hash})
+
+	return nil
+}
+
+func (r *deployReport) addSymbolUpload(path string, uploadErr error) {
+	upload := reportSymbolUpload{Path: path, Status: "success"}
+	if _, hash, err := fileSHA256(path); err == nil {
+		upload.SHA256 = hash
+	}
+	if uploadErr != nil {
+		upload.Status = "failed"
+		upload.Error = uploadErr.Error()
+	}
+
+	r.SymbolUploads = append(r.SymbolUploads, upload)
+}
+
+// setResult records the release, the mapping file upload and the destinations of a deploy.
+func (r *deployReport) setResult(result deployer.Result) {
+	if result.Release.ID != 0 {
+		release := result.Release
+		r.Release = &release
+	}
+
+	r.Destinations = result.Summary.Results
+
+	r.SymbolUploads = nil
+	if result.SymbolUpload != nil {
+		r.addSymbolUpload(result.SymbolUpload.Path, result.SymbolUpload.Err)
+	}
+}
+
+// write saves the report as JSON and Markdown to the deploy directory and returns their paths,
+// or empty paths if the deploy directory is not set.
+func (r *deployReport) write(status, errorMessage string, outputs map[string]string) (string, string, error) {
+	r.Status = status
+	r.Error = errorMessage
+	r.Outputs = outputs
+	r.FinishedAt = time.Now()
+
+	if r.deployDir == "" {
+		return "", "", nil
+	}
+
+	if err := os.MkdirAll(r.deployDir, 0755); err != nil {
+		return "", "", err
+	}
+
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", "", err
+	}
+
+	jsonPath := filepath.Join(r.deployDir, reportJSONFileName)
+	if err := os.WriteFile(jsonPath, b, 0644); err != nil {
+		return "", "", err
+	}
+
+	markdownPath := filepath.Join(r.deployDir, reportMarkdownFileName)
+	if err := os.WriteFile(markdownPath, []byte(r.markdown()), 0644); err != nil {
+		return "", "", err
+	}
+
+	return jsonPath, markdownPath, nil
+}
+
+func (r *deployReport) markdown() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# App Center deploy report\n\n")
+	fmt.Fprintf(&b, "- Status: **%s**\n", r.Status)
+	if r.Error != "" {
+		fmt.Fprintf(&b, "- Error: %s\n", r.Error)
+	}
+	fmt.Fprintf(&b, "- Mode: %s\n", r.Mode)
+	fmt.Fprintf(&b, "- Started at: %s\n", r.StartedAt.Format(time.RFC3339))
+	fmt.Fprintf(&b, "- Finished at: %s\n", r.FinishedAt.Format(time.RFC3339))
+
+	if r.Release != nil {
+		fmt.Fprintf(&b, "\n## Release\n\n")
+		fmt.Fprintf(&b, "| Field | Value |\n| --- | --- |\n")
+		fmt.Fprintf(&b, "| ID | %d |\n", r.Release.ID)
+		fmt.Fprintf(&b, "| Version | %s (%s) |\n", r.Release.ShortVersion, r.Release.Version)
+		fmt.Fprintf(&b, "| Package | %s |\n", r.Release.BundleIdentifier)
+		fmt.Fprintf(&b, "| Size | %d |\n", r.Release.Size)
+		fmt.Fprintf(&b, "| Min API level | %s |\n", r.Release.AndroidMinAPILevel)
+		fmt.Fprintf(&b, "| Fingerprint | %s |\n", r.Release.Fingerprint)
+		fmt.Fprintf(&b, "| Uploaded at | %s |\n", r.Release.UploadedAt)
+	}
+
+	if r.Comparison != nil {
+		fmt.Fprintf(&b, "\n## Comparison with release %d\n\n", r.Comparison.PreviousReleaseID)
+		fmt.Fprintf(&b, "| Field | Previous | Current | Change |\n| --- | --- | --- | --- |\n")
+		fmt.Fprintf(&b, "| Version | %s (%s) | %s (%s) | |\n", r.Comparison.PreviousShortVersion, r.Comparison.PreviousVersion, r.Comparison.ShortVersion, r.Comparison.Version)
+		fmt.Fprintf(&b, "| Size | %d | %d | %+d (%+.2f%%) |\n", r.Comparison.PreviousSize, r.Comparison.Size, r.Comparison.SizeDelta, r.Comparison.SizeDeltaPercent)
+		fmt.Fprintf(&b, "| Min API level | %s | %s | |\n", r.Comparison.PreviousMinAPILevel, r.Comparison.MinAPILevel)
+		for _, violation := range r.Comparison.Violations {
+			fmt.Fprintf(&b, "\n- Budget exceeded: %s\n", violation)
+		}
+	}
+
+	if len(r.Artifacts) > 0 {
+		fmt.Fprintf(&b, "\n## Artifacts\n\n")
+		fmt.Fprintf(&b, "| Path | Size | SHA-256 |\n| --- | --- | --- |\n")
+		for _, artifact := range r.Artifacts {
+			fmt.Fprintf(&b, "| %s | %d | `%s` |\n", artifact.Path, artifact.Size, artifact.SHA256)
+		}
+	}
+
+	if len(r.Destinations) > 0 {
+		fmt.Fprintf(&b, "\n## Destinations\n\n")
+		fmt.Fprintf(&b, "| Type | Name | Result |\n| --- | --- | --- |\n")
+		for _, dest := range r.Destinations {
+			result := "ok"
+			if dest.Error != "" {
+				result = "failed: " + dest.Error
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s |\n", dest.Type, dest.Name, result)
+		}
+	}
+
+	if len(r.SymbolUploads) > 0 {
+		fmt.Fprintf(&b, "\n## Symbol uploads\n\n")
+		fmt.Fprintf(&b, "| Path | Result |\n| --- | --- |\n")
+		for _, upload := range r.SymbolUploads {
+			result := upload.Status
+			if upload.Error != "" {
+				result += ": " + upload.Error
+			}
+			fmt.Fprintf(&b, "| %s | %s |\n", upload.Path, result)
+		}
+	}
+
+	if len(r.Phases) > 0 {
+		fmt.Fprintf(&b, "\n## Timings\n\n")
+		fmt.Fprintf(&b, "| Phase | Duration |\n| --- | --- |\n")
+		for _, phase := range r.Phases {
+			fmt.Fprintf(&b, "| %s | %s |\n", phase.Name, time.Duration(phase.DurationMS)*time.Millisecond)
+		}
+	}
+
+	fmt.Fprintf(&b, "\n## Inputs\n\n")
+	fmt.Fprintf(&b, "| Input | Value |\n| --- | --- |\n")
+	for _, key := range sortedKeys(r.Inputs) {
+		fmt.Fprintf(&b, "| %s | %s |\n", key, markdownCell(r.Inputs[key]))
+	}
+
+	return b.String()
+}
+
+func markdownCell(value string) string {
+	value = strings.ReplaceAll(value, "|", "\\|")
+	return strings.ReplaceAll(value, "\n", "<br>")
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func fileSHA256(path string) (int64, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return 0, "", err
+	}
+
+	return size, hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/client"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/model"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/deployer"
+)
+
+const (
+	modeRollback = "rollback"
+
+	disabledReleaseIDsEnvKey = "APPCENTER_ROLLBACK_DISABLED_RELEASE_IDS"
+	latestReleasesEnvKey     = "APPCENTER_ROLLBACK_LATEST_RELEASES"
+)
+
+// rollback disables the release given by release_id (or the latest release of each configured group),
+// optionally redistributes the previous enabled release to the configured groups,
+// and exports the latest release of each group.
+func rollback(cfg config, api client.API, appAPI appcenter.AppAPI, releaseOptions model.ReleaseOptions, plan deployer.Plan) {
+	var groups []model.Group
+	for _, planned := range plan.Groups {
+		if !planned.Found {
+			failf("Issue with input: distribution_group (%s): %s", planned.Name, plan.NotFoundError(planned))
+		}
+
+		groups = append(groups, planned.Group)
+	}
+
+	log.Infof("Finding the release(s) to disable")
+
+	var releaseIDs []int
+	if cfg.ReleaseID > 0 {
+		releaseIDs = append(releaseIDs, cfg.ReleaseID)
+	} else {
+		if len(groups) == 0 {
+			failf("Either release_id or distribution groups are required in rollback mode")
+		}
+
+		for _, group := range groups {
+			latest, err := appAPI.LatestReleaseInGroup(group.Name)
+			if err != nil {
+				failf("Failed to fetch the latest release of group (%s), error: %s", group.DisplayName, err)
+			}
+
+			if latest.ID == 0 {
+				log.Warnf("- %s: no release", group.DisplayName)
+				continue
+			}
+
+			log.Printf("- %s: %d (%s)", group.DisplayName, latest.ID, latest.ShortVersion)
+
+			if !containsInt(releaseIDs, latest.ID) {
+				releaseIDs = append(releaseIDs, latest.ID)
+			}
+		}
+	}
+
+	log.Donef("- Done")
+	fmt.Println()
+
+	if cfg.DryRun {
+		log.Infof("Rollback plan")
+		for _, id := range releaseIDs {
+			log.Printf("- Disable release: %d", id)
+		}
+		if cfg.RollbackRedistribute {
+			for _, group := range groups {
+				log.Printf("- Redistribute the previous enabled release to: %s", group.DisplayName)
+			}
+		}
+		fmt.Println()
+
+		log.Infof("Exporting outputs")
+		exportOutputs(map[string]string{statusEnvKey: dryRunStatus})
+		log.Donef("- Done")
+		log.Warnf("Dry run: no release was disabled")
+		return
+	}
+
+	log.Infof("Disabling release(s)")
+
+	for _, id := range releaseIDs {
+		log.Printf("- %d", id)
+
+		releaseAPI := appcenter.CreateReleaseAPI(api, model.Release{ID: id}, releaseOptions)
+		if err := releaseAPI.SetEnabled(false); err != nil {
+			failf("Failed to disable release (%d), error: %s", id, err)
+		}
+	}
+
+	log.Donef("- Done")
+	fmt.Println()
+
+	if cfg.RollbackRedistribute {
+		log.Infof("Redistributing the previous release(s)")
+
+		for _, group := range groups {
+			previous, err := previousEnabledRelease(appAPI, group, releaseIDs)
+			if err != nil {
+				failf("Failed to find the previous release of group (%s), error: %s", group.DisplayName, err)
+			}
+
+			if previous.ID == 0 {
+				log.Warnf("- %s: no previous enabled release", group.DisplayName)
+				continue
+			}
+
+			log.Printf("- %s: %d (%s)", group.DisplayName, previous.ID, previous.ShortVersion)
+
+			releaseAPI := appcenter.CreateReleaseAPI(api, previous, releaseOptions)
+			if err := releaseAPI.AddGroup(group); err != nil {
+				failf("Failed to add release (%d) to group (%s), error: %s", previous.ID, group.DisplayName, err)
+			}
+		}
+
+		log.Donef("- Done")
+		fmt.Println()
+	}
+
+	log.Infof("Fetching the latest release of the group(s)")
+
+	latestReleases := map[string]int{}
+	for _, group := range groups {
+		latest, err := appAPI.LatestReleaseInGroup(group.Name)
+		if err != nil {
+			failf("Failed to fetch the latest release of group (%s), error: %s", group.DisplayName, err)
+		}
+
+		log.Printf("- %s: %d", group.DisplayName, latest.ID)
+		latestReleases[group.Name] = latest.ID
+	}
+
+	log.Donef("- Done")
+	fmt.Println()
+
+	latestReleasesJSON, err := json.Marshal(latestReleases)
+	if err != nil {
+		failf("Failed to serialize the latest releases, error: %s", err)
+	}
+
+	var disabledIDs []string
+	for _, id := range releaseIDs {
+		disabledIDs = append(disabledIDs, strconv.Itoa(id))
+	}
+
+	log.Infof("Exporting outputs")
+
+	exportOutputs(map[string]string{
+		statusEnvKey:             "success",
+		disabledReleaseIDsEnvKey: strings.Join(disabledIDs, ","),
+		latestReleasesEnvKey:     string(latestReleasesJSON),
+	})
+
+	log.Donef("- Done")
+}
+
+// previousEnabledRelease returns the newest enabled release of the group which is not being rolled back,
+// or an empty release if there is none.
+func previousEnabledRelease(appAPI appcenter.AppAPI, group model.Group, excludedIDs []int) (model.Release, error) {
+	releases, err := appAPI.ReleasesInGroup(group.Name)
+	if err != nil {
+		return model.Release{}, err
+	}
+
+	sort.Slice(releases, func(i, j int) bool {
+		return releases[i].ID > releases[j].ID
+	})
+
+	for _, release := range releases {
+		if release.Enabled && !containsInt(excludedIDs, release.ID) {
+			return release, nil
+		}
+	}
+
+	return model.Release{}, nil
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
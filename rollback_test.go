@@ -0,0 +1,171 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/client"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/fake"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/model"
+)
+
+// addRelease adds a release distributed to the given groups to the fake app.
+func addRelease(app *fake.App, id int, enabled bool, groups ...string) {
+	app.Releases = append(app.Releases, &fake.Release{
+		Release: model.Release{ID: id, Version: "1", ShortVersion: "1.0", Enabled: enabled},
+		Groups:  groups,
+	})
+}
+
+func Test_previousEnabledRelease(t *testing.T) {
+	tests := []struct {
+		name        string
+		releases    []fake.Release
+		excludedIDs []int
+		want        int
+	}{
+		{
+			name:     "newest enabled release",
+			releases: []fake.Release{{Release: model.Release{ID: 1, Enabled: true}}, {Release: model.Release{ID: 3, Enabled: true}}, {Release: model.Release{ID: 2, Enabled: true}}},
+			want:     3,
+		},
+		{
+			name:        "rolled back release is skipped",
+			releases:    []fake.Release{{Release: model.Release{ID: 1, Enabled: true}}, {Release: model.Release{ID: 2, Enabled: true}}},
+			excludedIDs: []int{2},
+			want:        1,
+		},
+		{
+			name:        "disabled release is skipped",
+			releases:    []fake.Release{{Release: model.Release{ID: 1, Enabled: true}}, {Release: model.Release{ID: 2, Enabled: false}}, {Release: model.Release{ID: 3, Enabled: true}}},
+			excludedIDs: []int{3},
+			want:        1,
+		},
+		{
+			name:        "no enabled release",
+			releases:    []fake.Release{{Release: model.Release{ID: 1, Enabled: false}}, {Release: model.Release{ID: 2, Enabled: true}}},
+			excludedIDs: []int{2},
+			want:        0,
+		},
+		{
+			name: "no release in the group",
+			want: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := fake.NewServer()
+			defer server.Close()
+
+			app := server.AddApp("owner", "app")
+			group := app.AddGroup("Beta", false)
+			for _, release := range tt.releases {
+				addRelease(app, release.ID, release.Enabled, "Beta")
+			}
+			// Releases of other groups are never redistributed.
+			addRelease(app, 10, true, "Other")
+
+			api, err := client.CreateAPIWithClientParams(fake.Token, client.WithBaseURL(server.URL))
+			if err != nil {
+				t.Fatal(err)
+			}
+			appAPI := appcenter.CreateApplicationAPI(api, model.ReleaseOptions{App: model.App{Owner: "owner", AppName: "app"}})
+
+			got, err := previousEnabledRelease(appAPI, group, tt.excludedIDs)
+			if err != nil {
+				t.Fatalf("previousEnabledRelease() error = %v", err)
+			}
+			if got.ID != tt.want {
+				t.Errorf("previousEnabledRelease() = %d, want %d", got.ID, tt.want)
+			}
+		})
+	}
+}
+
+func Test_main_rollback(t *testing.T) {
+	tests := []struct {
+		name            string
+		inputs          map[string]string
+		wantDisabledIDs string
+		wantLatest      string
+		wantEnabled     map[int]bool
+		// wantRedistributed are the releases added to a group again.
+		wantRedistributed []string
+	}{
+		{
+			name:            "latest release of each group",
+			inputs:          map[string]string{"distribution_group": "Beta\nAlpha"},
+			wantDisabledIDs: "4,3",
+			wantLatest:      `{"Alpha":0,"Beta":2}`,
+			wantEnabled:     map[int]bool{1: true, 2: true, 3: false, 4: false},
+		},
+		{
+			name:              "latest release of each group redistributes the previous enabled one",
+			inputs:            map[string]string{"distribution_group": "Beta\nAlpha", "rollback_redistribute": "yes"},
+			wantDisabledIDs:   "4,3",
+			wantLatest:        `{"Alpha":0,"Beta":2}`,
+			wantEnabled:       map[int]bool{1: true, 2: true, 3: false, 4: false},
+			wantRedistributed: []string{"POST /v0.1/apps/owner/app/releases/2/groups"},
+		},
+		{
+			name:            "release_id",
+			inputs:          map[string]string{"release_id": "2", "distribution_group": "Beta"},
+			wantDisabledIDs: "2",
+			wantLatest:      `{"Beta":4}`,
+			wantEnabled:     map[int]bool{1: true, 2: false, 3: true, 4: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := fake.NewServer()
+			defer server.Close()
+
+			app := server.AddApp("owner", "app")
+			app.AddGroup("Beta", false)
+			app.AddGroup("Alpha", false)
+			addRelease(app, 1, true, "Beta")
+			addRelease(app, 2, true, "Beta")
+			addRelease(app, 3, true, "Alpha")
+			addRelease(app, 4, true, "Beta")
+
+			inputs := map[string]string{"mode": "rollback", "app_path": ""}
+			for key, value := range tt.inputs {
+				inputs[key] = value
+			}
+
+			run := runStep(t, server, inputs)
+			if run.exitCode != 0 {
+				t.Fatalf("step failed with exit code %d:\n%s", run.exitCode, run.log)
+			}
+
+			if got := run.outputs["APPCENTER_ROLLBACK_DISABLED_RELEASE_IDS"]; got != tt.wantDisabledIDs {
+				t.Errorf("output APPCENTER_ROLLBACK_DISABLED_RELEASE_IDS = %q, want %q", got, tt.wantDisabledIDs)
+			}
+			if got := run.outputs["APPCENTER_ROLLBACK_LATEST_RELEASES"]; got != tt.wantLatest {
+				t.Errorf("output APPCENTER_ROLLBACK_LATEST_RELEASES = %q, want %q", got, tt.wantLatest)
+			}
+
+			for id, wantEnabled := range tt.wantEnabled {
+				release, ok := server.Release("owner", "app", id)
+				if !ok {
+					t.Fatalf("release %d not found", id)
+				}
+				if release.Enabled != wantEnabled {
+					t.Errorf("release %d enabled = %t, want %t", id, release.Enabled, wantEnabled)
+				}
+			}
+
+			var redistributed []string
+			for _, request := range requested(server) {
+				if strings.HasSuffix(request, "/groups") && strings.HasPrefix(request, "POST ") {
+					redistributed = append(redistributed, request)
+				}
+			}
+			if !reflect.DeepEqual(redistributed, tt.wantRedistributed) {
+				t.Errorf("redistributed releases = %v, want %v", redistributed, tt.wantRedistributed)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/model"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/util"
+)
+
+// baseURLs are the validated base URLs of the App Center portal and the install pages.
+type baseURLs struct {
+	portal  string
+	install string
+}
+
+func newBaseURLs(portalBaseURL, installBaseURL string) (baseURLs, error) {
+	portal, err := util.ParseBaseURL(portalBaseURL)
+	if err != nil {
+		return baseURLs{}, fmt.Errorf("portal_base_url: %s", err)
+	}
+
+	install, err := util.ParseBaseURL(installBaseURL)
+	if err != nil {
+		return baseURLs{}, fmt.Errorf("install_base_url: %s", err)
+	}
+
+	return baseURLs{portal: portal, install: install}, nil
+}
+
+// appURLs builds the App Center portal and install page URLs of an app.
+// Every exported URL should be assembled here, so the owner type and escaping rules are applied consistently.
+type appURLs struct {
+	baseURLs     baseURLs
+	ownerSegment string
+	ownerName    string
+	appName      string
+}
+
+// newAppURLs uses the canonical owner and app names returned by the apps API,
+// falling back to the configured ones if the API did not return them.
+func newAppURLs(base baseURLs, details model.AppDetails, app model.App) appURLs {
+	urls := appURLs{
+		baseURLs:     base,
+		ownerSegment: ownerPathSegment(details.Owner.Type),
+		ownerName:    details.Owner.Name,
+		appName:      details.Name,
+	}
+
+	if urls.ownerName == "" {
+		urls.ownerName = app.Owner
+	}
+	if urls.appName == "" {
+		urls.appName = app.AppName
+	}
+
+	return urls
+}
+
+func ownerPathSegment(ownerType model.OwnerType) string {
+	if ownerType == model.OwnerTypeOrg {
+		return "orgs"
+	}
+
+	return "users"
+}
+
+// releasePage returns the release's page in the App Center portal.
+func (u appURLs) releasePage(releaseID int) string {
+	return u.build(u.baseURLs.portal, "apps", u.appName, "distribute", "releases", fmt.Sprint(releaseID))
+}
+
+// publicInstallPage returns the public install page of a distribution group.
+func (u appURLs) publicInstallPage(group model.Group) string {
+	name := group.Name
+	if name == "" {
+		name = group.DisplayName
+	}
+
+	return u.build(u.baseURLs.install, "apps", u.appName, "distribution_groups", name)
+}
+
+func (u appURLs) build(baseURL string, segments ...string) string {
+	escaped := []string{u.ownerSegment, url.PathEscape(u.ownerName)}
+	for _, segment := range segments {
+		escaped = append(escaped, url.PathEscape(segment))
+	}
+
+	return baseURL + "/" + strings.Join(escaped, "/")
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/model"
+)
+
+func Test_appURLs(t *testing.T) {
+	base := baseURLs{portal: "https://appcenter.ms", install: "https://install.appcenter.ms"}
+	app := model.App{Owner: "configured-owner", AppName: "configured-app"}
+
+	tests := []struct {
+		name              string
+		details           model.AppDetails
+		group             model.Group
+		wantReleasePage   string
+		wantPublicInstall string
+	}{
+		{
+			name: "user-owned app",
+			details: model.AppDetails{
+				Name:  "My-App",
+				Owner: model.AppOwner{Name: "john.doe", Type: model.OwnerTypeUser},
+			},
+			group:             model.Group{Name: "Public"},
+			wantReleasePage:   "https://appcenter.ms/users/john.doe/apps/My-App/distribute/releases/42",
+			wantPublicInstall: "https://install.appcenter.ms/users/john.doe/apps/My-App/distribution_groups/Public",
+		},
+		{
+			name: "org-owned app",
+			details: model.AppDetails{
+				Name:  "My-App",
+				Owner: model.AppOwner{Name: "bitrise", Type: model.OwnerTypeOrg},
+			},
+			group:             model.Group{Name: "Public"},
+			wantReleasePage:   "https://appcenter.ms/orgs/bitrise/apps/My-App/distribute/releases/42",
+			wantPublicInstall: "https://install.appcenter.ms/orgs/bitrise/apps/My-App/distribution_groups/Public",
+		},
+		{
+			name: "group name with spaces",
+			details: model.AppDetails{
+				Name:  "My-App",
+				Owner: model.AppOwner{Name: "bitrise", Type: model.OwnerTypeOrg},
+			},
+			group:             model.Group{Name: "Beta Testers"},
+			wantReleasePage:   "https://appcenter.ms/orgs/bitrise/apps/My-App/distribute/releases/42",
+			wantPublicInstall: "https://install.appcenter.ms/orgs/bitrise/apps/My-App/distribution_groups/Beta%20Testers",
+		},
+		{
+			name:              "falls back to the configured names and the group's display name",
+			details:           model.AppDetails{},
+			group:             model.Group{DisplayName: "Public"},
+			wantReleasePage:   "https://appcenter.ms/users/configured-owner/apps/configured-app/distribute/releases/42",
+			wantPublicInstall: "https://install.appcenter.ms/users/configured-owner/apps/configured-app/distribution_groups/Public",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			urls := newAppURLs(base, tt.details, app)
+
+			if got := urls.releasePage(42); got != tt.wantReleasePage {
+				t.Errorf("releasePage() = %s, want %s", got, tt.wantReleasePage)
+			}
+			if got := urls.publicInstallPage(tt.group); got != tt.wantPublicInstall {
+				t.Errorf("publicInstallPage() = %s, want %s", got, tt.wantPublicInstall)
+			}
+		})
+	}
+}
+
+func Test_newBaseURLs(t *testing.T) {
+	base, err := newBaseURLs("https://gateway.example.com/appcenter/", "https://install.example.com")
+	if err != nil {
+		t.Fatalf("newBaseURLs() error = %s", err)
+	}
+
+	urls := newAppURLs(base, model.AppDetails{Name: "app", Owner: model.AppOwner{Name: "owner", Type: model.OwnerTypeOrg}}, model.App{})
+	if got, want := urls.releasePage(1), "https://gateway.example.com/appcenter/orgs/owner/apps/app/distribute/releases/1"; got != want {
+		t.Errorf("releasePage() = %s, want %s", got, want)
+	}
+
+	if _, err := newBaseURLs("not a url", "https://install.example.com"); err == nil {
+		t.Errorf("newBaseURLs() expected an error for an invalid portal URL")
+	}
+}
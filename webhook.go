@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"text/template"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-utils/retry"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/client"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/appcenter/model"
+	"github.com/bitrise-steplib/steps-appcenter-deploy-android/deployer"
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+const (
+	webhookOnSuccess = "success"
+	webhookOnFailure = "failure"
+
+	webhookRetryMax = 3
+
+	defaultWebhookTemplate = `{"status":{{ json .Status }},"app":{{ json .App.AppName }},"owner":{{ json .App.Owner }},"release_id":{{ json .Outputs.APPCENTER_DEPLOY_RELEASE_ID }},"version":{{ json .Outputs.APPCENTER_DEPLOY_SHORT_VERSION }},"install_url":{{ json .Outputs.APPCENTER_DEPLOY_INSTALL_URL }},"error":{{ json .Error }}}`
+)
+
+// webhookPayloadData is available for the webhook template.
+type webhookPayloadData struct {
+	Status       string
+	Error        string
+	App          model.App
+	Release      *model.Release
+	Destinations []deployer.DestinationResult
+	Outputs      map[string]string
+}
+
+// webhookNotifier posts the result of the run to a webhook.
+type webhookNotifier struct {
+	url         string
+	app         model.App
+	template    *template.Template
+	on          string
+	failOnError bool
+	sent        bool
+}
+
+// webhook is the notifier of the current run, it is nil if no webhook is configured.
+var webhook *webhookNotifier
+
+func newWebhookNotifier(cfg config) (*webhookNotifier, error) {
+	if cfg.WebhookURL == "" {
+		return nil, nil
+	}
+
+	text := cfg.WebhookTemplate
+	if text == "" {
+		text = defaultWebhookTemplate
+	}
+
+	tmpl, err := template.New("webhook").Option("missingkey=zero").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook template: %s", err)
+	}
+
+	return &webhookNotifier{
+		url:         string(cfg.WebhookURL),
+		app:         model.App{Owner: cfg.OwnerName, AppName: cfg.AppName, AppType: model.AppTypeAndroid},
+		template:    tmpl,
+		on:          cfg.WebhookOn,
+		failOnError: cfg.WebhookFailOnError,
+	}, nil
+}
+
+func (w *webhookNotifier) shouldSend(failed bool) bool {
+	switch w.on {
+	case webhookOnSuccess:
+		return !failed
+	case webhookOnFailure:
+		return failed
+	default:
+		return true
+	}
+}
+
+// notify renders the payload and posts it to the webhook, retrying on network errors and server errors.
+// The webhook is called at most once per run.
+func (w *webhookNotifier) notify(data webhookPayloadData) error {
+	failed := data.Status == "failed"
+	if w.sent || !w.shouldSend(failed) {
+		return nil
+	}
+	w.sent = true
+	data.App = w.app
+
+	var payload bytes.Buffer
+	if err := w.template.Execute(&payload, data); err != nil {
+		return fmt.Errorf("failed to render webhook payload: %s", err)
+	}
+
+	req, err := retryablehttp.NewRequest(http.MethodPost, w.url, payload.Bytes())
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	httpClient := retry.NewHTTPClient()
+	httpClient.Logger = client.RedactingLogger{}
+	httpClient.RetryMax = webhookRetryMax
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Warnf("failed to close body: %s", err)
+		}
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("invalid status code: %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// notifyWebhook calls the webhook of the run with its result.
+// Errors are logged as warnings, the returned error is only non-nil if webhook_fail_on_error is enabled.
+func (r runReporting) notifyWebhook(status, errorMessage string, outputs map[string]string) error {
+	webhook, report := r.webhook, r.report
+	if webhook == nil || webhook.sent || !webhook.shouldSend(status == "failed") {
+		return nil
+	}
+
+	data := webhookPayloadData{
+		Status:  status,
+		Error:   errorMessage,
+		Outputs: outputs,
+	}
+	if report != nil {
+		data.Release = report.Release
+		data.Destinations = report.Destinations
+	}
+
+	if err := webhook.notify(data); err != nil {
+		log.Warnf("Failed to call webhook, error: %s", client.Redact(err.Error()))
+		if webhook.failOnError {
+			return err
+		}
+		return nil
+	}
+
+	log.Printf("- Webhook called")
+
+	return nil
+}